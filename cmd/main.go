@@ -3,11 +3,24 @@ package main
 import (
 	"client-go/config"
 	"client-go/internal/app/checking_deployment"
+	"client-go/internal/app/cost"
 	evictedpod "client-go/internal/app/evicted_pod"
+	"client-go/internal/app/lint"
 	"client-go/internal/app/node"
 	"client-go/internal/app/pod_metadata"
+	"client-go/internal/app/rightsizing"
+	"client-go/internal/app/slack_command"
+	"client-go/internal/app/snapshot"
+	"client-go/internal/pkg/notifier"
+	"client-go/internal/pkg/report"
 
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
@@ -59,17 +72,69 @@ func main() {
 
 	app.Get("/metrics", monitor.New())
 
+	notifiers, err := notifier.FromEnv()
+	if err != nil {
+		log.Fatalf("invalid notifier configuration: %v", err)
+	}
+
+	watcherOpts, err := checking_deployment.WatcherOptionsFromEnv()
+	if err != nil {
+		log.Fatalf("invalid watcher configuration: %v", err)
+	}
+	changeHistory := watcherOpts.History
+	changeWatcher := checking_deployment.NewWatcher(clientSet, notifiers, watcherOpts)
+	if os.Getenv("WATCHER_START_ON_BOOT") == "true" {
+		changeWatcher.Start()
+	}
+
 	apiV1 := app.Group("/api/v1")
 
+	reportStore := report.NewStoreFromEnv()
+	snapshotStore := snapshot.NewStoreFromEnv()
+
+	// 리포트 기본 옵션(namespace scope 등)은 서버 설정이므로 시작할 때 한 번만 검증한다
+	reportDefaults, err := pod_metadata.OptionsFromEnv()
+	if err != nil {
+		log.Fatalf("invalid report configuration: %v", err)
+	}
+
+	// SNAPSHOT_INTERVAL(예: 24h) 이 설정되어 있으면 주기적으로 인벤토리 스냅샷을 저장
+	if interval := os.Getenv("SNAPSHOT_INTERVAL"); interval != "" {
+		snapshotInterval, err := time.ParseDuration(interval)
+		if err != nil {
+			log.Fatalf("invalid SNAPSHOT_INTERVAL: %v", err)
+		}
+		go snapshot.RunPeriodically(clientSet, snapshotStore, reportDefaults, snapshotInterval)
+	}
+
 	apiV1.Get("/evicted-pods", func(c *fiber.Ctx) error {
-		deletedPods, err := evictedpod.EvictedPods(clientSet)
+		opts := evictedpod.OptionsFromEnv()
+		if workers := c.QueryInt("workers"); workers > 0 {
+			opts.Workers = workers
+		}
+		if qps := c.QueryFloat("qps"); qps > 0 {
+			opts.QPS = float32(qps)
+		}
+		summary, err := evictedpod.EvictedPods(clientSet, opts)
 		if err != nil {
 			log.Error(err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"msg": err.Error(),
 			})
 		}
-		return c.Status(fiber.StatusOK).JSON(deletedPods)
+		if summary.Total > 0 {
+			severity := notifier.SeverityInfo
+			if summary.Failed > 0 {
+				severity = notifier.SeverityWarning
+			}
+			notify(notifiers, notifier.Message{
+				Event:    "evicted-pods.cleaned",
+				Title:    "Evicted Pod Cleanup",
+				Text:     fmt.Sprintf("Deleted `%d`, already gone `%d`, failed `%d` of `%d` evicted pods", summary.Deleted, summary.AlreadyGone, summary.Failed, summary.Total),
+				Severity: severity,
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(summary)
 	})
 
 	apiV1.Get("/node-disk-usage", func(c *fiber.Ctx) error {
@@ -112,32 +177,333 @@ func main() {
 			go func() {
 				_, err := node.NodeDrain(clientSet, percentage, dryRun)
 				if err != nil {
-					// 비동기 처리 중 오류가 발생한 경우, 여기서는 로그만 기록하고 있습니다.
+					// 비동기 처리 중 오류가 발생한 경우, 로그를 남기고 알림으로 결과를 전달합니다.
 					log.Error(err)
-					// 클라이언트에게 즉시 응답이 반환되므로, 오류 처리를 위한 콜백이나 상태 조회 API가 필요...할수도 있습니다..
+					notify(notifiers, notifier.Message{
+						Event:    "node-drain.failed",
+						Title:    "Node Drain Failed",
+						Text:     fmt.Sprintf("Drain of nodes under `%s%%` memory usage failed: %v", percentage, err),
+						Severity: notifier.SeverityCritical,
+					})
+					return
 				}
+				notify(notifiers, notifier.Message{
+					Event:    "node-drain.completed",
+					Title:    "Node Drain Completed",
+					Text:     fmt.Sprintf("Drain of nodes under `%s%%` memory usage completed", percentage),
+					Severity: notifier.SeverityInfo,
+				})
 			}()
 		}
 		return c.Status(fiber.StatusAccepted).SendString("Node drain process started")
 	})
 
+	// 이전 버전과의 호환을 위해 남겨둔 엔드포인트. watcher 가 이미 실행 중이면 새로 띄우지 않는다
 	apiV1.Get("/checking-container-image", func(c *fiber.Ctx) error {
-		// 고루틴을 사용하여 NodeDrain 함수를 비동기적으로 실행
-		go func() {
-			checking_deployment.CheckingContainerImage(clientSet)
-		}()
+		if !changeWatcher.Start() {
+			return c.Status(fiber.StatusOK).SendString("Checking container image process is already running")
+		}
 		return c.Status(fiber.StatusAccepted).SendString("Checking container image process started")
 	})
 
+	apiV1.Post("/watcher/start", func(c *fiber.Ctx) error {
+		started := changeWatcher.Start()
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"started": started,
+			"status":  changeWatcher.Status(),
+		})
+	})
+
+	apiV1.Post("/watcher/stop", func(c *fiber.Ctx) error {
+		stopped := changeWatcher.Stop()
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"stopped": stopped,
+			"status":  changeWatcher.Status(),
+		})
+	})
+
+	apiV1.Get("/watcher/status", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(changeWatcher.Status())
+	})
+
+	// 감지한 변경 이력. since/until 은 RFC3339 시각 또는 지금으로부터의 기간(예: 24h)
+	apiV1.Get("/deployment-changes", func(c *fiber.Ctx) error {
+		filter := checking_deployment.HistoryFilter{
+			Namespace: c.Query("namespace"),
+			Workload:  c.Query("workload"),
+			Kind:      c.Query("kind"),
+			Container: c.Query("container"),
+			Image:     c.Query("image"),
+			Actor:     c.Query("actor"),
+			Limit:     c.QueryInt("limit"),
+			// violations=true 면 이미지 정책 위반 레코드만
+			ViolationsOnly: c.QueryBool("violations"),
+		}
+		var err error
+		if filter.Since, err = parseTimeQuery(c.Query("since")); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		if filter.Until, err = parseTimeQuery(c.Query("until")); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+
+		changes, err := changeHistory.Query(filter)
+		if err != nil {
+			log.Error(err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(changes)
+	})
+
+	// 재시도 끝에 보내지 못한 알림 목록
+	apiV1.Get("/notifications/dead-letters", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(notifiers.DeadLetters.List())
+	})
+
+	slackCommands := slack_command.NewHandler(clientSet, notifiers, slack_command.OptionsFromEnv())
+
+	// Slack slash command. 서명 검증 후 바로 응답하고, 결과는 response_url 로 따로 보낸다
+	apiV1.Post("/slack/commands", func(c *fiber.Ctx) error {
+		if err := slackCommands.Verify(c.Get("X-Slack-Request-Timestamp"), c.Get("X-Slack-Signature"), c.Body()); err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		response := slackCommands.HandleCommand(slack_command.CommandRequest{
+			Command:     c.FormValue("command"),
+			Text:        c.FormValue("text"),
+			UserID:      c.FormValue("user_id"),
+			UserName:    c.FormValue("user_name"),
+			ResponseURL: c.FormValue("response_url"),
+		})
+		return c.Status(fiber.StatusOK).JSON(response)
+	})
+
+	// Slack interactive action (확인 버튼)
+	apiV1.Post("/slack/actions", func(c *fiber.Ctx) error {
+		if err := slackCommands.Verify(c.Get("X-Slack-Request-Timestamp"), c.Get("X-Slack-Signature"), c.Body()); err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		if err := slackCommands.HandleAction(c.FormValue("payload")); err != nil {
+			log.Error(err)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
 	apiV1.Get("/pod-metadata", func(c *fiber.Ctx) error {
-		err := pod_metadata.GetPodMetadata(clientSet)
+		opts, err := reportOptionsFromQuery(c, reportDefaults)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		resourceReport, err := pod_metadata.GetPodMetadata(clientSet, opts)
+		if err != nil {
+			log.Error(err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		return sendReport(c, reportStore, "workload_resources", resourceReport)
+	})
+
+	apiV1.Get("/rightsizing", func(c *fiber.Ctx) error {
+		opts, err := rightsizingOptionsFromQuery(c, reportDefaults)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		sizingReport, err := rightsizing.GetRightsizingReport(clientSet, opts)
+		if err != nil {
+			log.Error(err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		return sendReport(c, reportStore, "rightsizing", sizingReport)
+	})
+
+	apiV1.Get("/cost", func(c *fiber.Ctx) error {
+		costOpts, err := costOptionsFromQuery(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		costReport, err := cost.GetCostAllocation(clientSet, costOpts)
+		if err != nil {
+			log.Error(err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(costReport)
+	})
+
+	apiV1.Get("/cost/export", func(c *fiber.Ctx) error {
+		costOpts, err := costOptionsFromQuery(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		costReport, err := cost.GetCostAllocation(clientSet, costOpts)
+		if err != nil {
+			log.Error(err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		return sendReport(c, reportStore, "cost_by_"+costReport.GroupBy, costReport)
+	})
+
+	apiV1.Get("/lint", func(c *fiber.Ctx) error {
+		reportOpts, err := reportOptionsFromQuery(c, reportDefaults)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		lintConfig, err := lint.ConfigFromEnv()
+		if err != nil {
+			log.Error(err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		if ratio := c.QueryFloat("maxLimitRequestRatio"); ratio > 0 {
+			lintConfig.MaxLimitRequestRatio = ratio
+		}
+		result, err := lint.Lint(clientSet, lintConfig, lint.Options{
+			Scope:       reportOpts.Scope,
+			MinSeverity: c.Query("severity"),
+			Rules:       pod_metadata.SplitList(c.Query("rules")),
+		})
+		if errors.Is(err, lint.ErrInvalidOptions) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		if err != nil {
+			log.Error(err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(result)
+	})
+
+	apiV1.Post("/snapshots", func(c *fiber.Ctx) error {
+		opts, err := reportOptionsFromQuery(c, reportDefaults)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		snap, err := snapshot.Take(clientSet, snapshotStore, opts)
 		if err != nil {
 			log.Error(err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"msg": err.Error(),
 			})
 		}
-		return c.Status(fiber.StatusAccepted).SendString("pod metadata")
+		return c.Status(fiber.StatusCreated).JSON(snapshot.Totals(snap))
+	})
+
+	apiV1.Get("/snapshots", func(c *fiber.Ctx) error {
+		ids, err := snapshotStore.IDs()
+		if err != nil {
+			log.Error(err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"snapshots": ids,
+		})
+	})
+
+	apiV1.Get("/snapshots/diff", func(c *fiber.Ctx) error {
+		diff, err := snapshot.DiffByID(snapshotStore, c.Query("from"), c.Query("to"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(diff)
+	})
+
+	apiV1.Get("/snapshots/totals", func(c *fiber.Ctx) error {
+		history, err := snapshot.History(snapshotStore)
+		if err != nil {
+			log.Error(err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"totals": history,
+		})
+	})
+
+	apiV1.Get("/snapshots/:id", func(c *fiber.Ctx) error {
+		snap, err := snapshotStore.Load(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		return sendReport(c, reportStore, "inventory_"+snap.ID, snap.Inventory)
+	})
+
+	apiV1.Get("/namespace-scopes", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"presets": pod_metadata.ScopePresetNames(),
+		})
+	})
+
+	apiV1.Get("/reports", func(c *fiber.Ctx) error {
+		reports, err := reportStore.List()
+		if err != nil {
+			log.Error(err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		links := make([]fiber.Map, 0, len(reports))
+		for _, r := range reports {
+			links = append(links, fiber.Map{
+				"name":      r.Name,
+				"size":      r.Size,
+				"createdAt": r.CreatedAt,
+				"download":  reportDownloadPath(r.Name),
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"reports": links,
+		})
+	})
+
+	apiV1.Get("/reports/:name", func(c *fiber.Ctx) error {
+		path, err := reportStore.Path(c.Params("name"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		return c.Download(path)
 	})
 
 	apiV1.Get("/node-pod-count", func(c *fiber.Ctx) error {
@@ -172,3 +538,137 @@ func main() {
 
 	log.Fatal(app.Listen(":3000"))
 }
+
+// negotiateFormat 은 format 쿼리를 우선 사용하고, 없으면 Accept 헤더로 리포트 형식을 고른다
+func negotiateFormat(c *fiber.Ctx) (report.Format, error) {
+	if format := c.Query("format"); format != "" {
+		return report.ParseFormat(format)
+	}
+	format, ok := report.FormatFromContentType(c.Accepts(report.ContentTypes()...))
+	if !ok {
+		return "", fmt.Errorf("none of %v is acceptable", report.ContentTypes())
+	}
+	return format, nil
+}
+
+// sendReport 는 협상된 형식으로 리포트를 응답에 쓴다. 리포트는 이미 메모리에 만들어진 상태이고,
+// 응답 본문만 직렬화하면서 바로 내보내 별도의 버퍼를 만들지 않는다.
+// persist=true 이면 REPORT_DIR 에 사본을 저장하고 Link 헤더로 다운로드 경로를 알려준다.
+func sendReport(c *fiber.Ctx, store report.Store, prefix string, table report.Table) error {
+	format, err := negotiateFormat(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotAcceptable).JSON(fiber.Map{
+			"msg": err.Error(),
+		})
+	}
+
+	if c.QueryBool("persist") {
+		if !store.Enabled() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"msg": "persist=true requires a report directory: " + report.ErrStoreDisabled.Error(),
+			})
+		}
+		name, err := store.Save(prefix, format, table)
+		if err != nil {
+			log.Error(err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"msg": err.Error(),
+			})
+		}
+		c.Append(fiber.HeaderLink, fmt.Sprintf("<%s>; rel=\"alternate\"", reportDownloadPath(name)))
+	}
+
+	c.Set(fiber.HeaderContentType, format.ContentType())
+	if format == report.CSV {
+		c.Attachment(prefix + format.Extension())
+	}
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := report.Write(w, format, table); err != nil {
+			log.Error(err)
+		}
+	})
+	return nil
+}
+
+func reportDownloadPath(name string) string {
+	return "/api/v1/reports/" + name
+}
+
+// reportOptionsFromQuery 는 시작할 때 읽은 기본값에 요청별 namespace scope 와 사이드카 제외 설정을 덮어쓴다.
+// scope=<preset> 또는 nsPrefix, nsRegex, namespaces, nsSelector 쿼리를 사용할 수 있다. 반환하는 오류는 모두 쿼리 오류다.
+func reportOptionsFromQuery(c *fiber.Ctx, defaults pod_metadata.ReportOptions) (pod_metadata.ReportOptions, error) {
+	opts := defaults
+	var err error
+
+	if exclude := c.Query("excludeContainers"); exclude != "" {
+		opts.ExcludedContainers = pod_metadata.SplitList(exclude)
+	}
+
+	if preset := c.Query("scope"); preset != "" {
+		if opts.Scope, err = pod_metadata.ScopePreset(preset); err != nil {
+			return opts, err
+		}
+	}
+	custom := pod_metadata.NamespaceScope{
+		Prefixes:      pod_metadata.SplitList(c.Query("nsPrefix")),
+		Regexes:       pod_metadata.SplitList(c.Query("nsRegex")),
+		Names:         pod_metadata.SplitList(c.Query("namespaces")),
+		LabelSelector: c.Query("nsSelector"),
+	}
+	if !custom.IsEmpty() {
+		opts.Scope = custom
+	}
+	return opts, opts.Scope.Validate()
+}
+
+func costOptionsFromQuery(c *fiber.Ctx) (cost.Options, error) {
+	opts := cost.OptionsFromEnv()
+	if groupBy := c.Query("groupBy"); groupBy != "" {
+		opts.GroupBy = groupBy
+	}
+	if teamLabel := c.Query("teamLabel"); teamLabel != "" {
+		opts.TeamLabel = teamLabel
+	}
+	return opts, opts.Validate()
+}
+
+// rightsizingOptionsFromQuery 는 환경 변수 기본값에 scope, window, headroom 쿼리를 덮어쓴다. 반환하는 오류는 모두 쿼리 오류다
+func rightsizingOptionsFromQuery(c *fiber.Ctx, defaults pod_metadata.ReportOptions) (rightsizing.Options, error) {
+	opts := rightsizing.OptionsFromEnv()
+	reportOpts, err := reportOptionsFromQuery(c, defaults)
+	if err != nil {
+		return opts, err
+	}
+	opts.Scope = reportOpts.Scope
+	if window := c.Query("window"); window != "" {
+		opts.Window = window
+	}
+	if headroom := c.Query("headroom"); headroom != "" {
+		if opts.Headroom, err = strconv.ParseFloat(headroom, 64); err != nil {
+			return opts, fmt.Errorf("invalid headroom %q: %w", headroom, err)
+		}
+	}
+	return opts, opts.Validate()
+}
+
+// parseTimeQuery 는 RFC3339 시각이나 "24h" 같은 기간(지금으로부터 그만큼 전)을 받는다. 빈 값은 zero time
+func parseTimeQuery(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use RFC3339 or a duration such as 24h", value)
+	}
+	return time.Now().Add(-d), nil
+}
+
+// notify 는 알림 전송 실패를 로그로만 남긴다. 알림 실패가 API 응답을 바꾸지 않도록 하기 위함
+func notify(n notifier.Notifier, msg notifier.Message) {
+	if err := n.Notify(context.Background(), msg); err != nil {
+		log.Error(err)
+	}
+}
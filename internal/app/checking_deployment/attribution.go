@@ -0,0 +1,143 @@
+package checking_deployment
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	changeCauseAnnotation     = "kubernetes.io/change-cause"
+	argoTrackingIDAnnotation  = "argocd.argoproj.io/tracking-id"
+	argoInstanceLabel         = "argocd.argoproj.io/instance"
+	appInstanceLabel          = "app.kubernetes.io/instance"
+	managedByLabel            = "app.kubernetes.io/managed-by"
+	helmReleaseNameAnnotation = "meta.helm.sh/release-name"
+	helmReleaseNSAnnotation   = "meta.helm.sh/release-namespace"
+	helmChartLabel            = "helm.sh/chart"
+)
+
+// attributionType 은 변경을 누가, 어떤 경로로 했는지에 대한 단서.
+// revision 은 deployment controller 가 나중에 올리므로 여기서 보지 않고 rollout 알림에서 보낸다
+type attributionType struct {
+	// Managers 는 이번 업데이트에서 managedFields 가 바뀐 field manager ("kubectl-edit (Update)" 형태)
+	Managers    []string
+	ChangeCause string
+	ArgoCDApp   string
+	HelmRelease string
+	HelmChart   string
+}
+
+func attributeChange(oldObj, newObj metaV1.Object) attributionType {
+	annotations := newObj.GetAnnotations()
+	labels := newObj.GetLabels()
+
+	attribution := attributionType{
+		Managers:    changedManagers(oldObj.GetManagedFields(), newObj.GetManagedFields()),
+		ChangeCause: annotations[changeCauseAnnotation],
+	}
+
+	// Argo CD 는 설정에 따라 annotation 또는 label 로 추적한다. tracking-id 는 "app:group/kind:ns/name" 형태
+	if trackingID := annotations[argoTrackingIDAnnotation]; trackingID != "" {
+		attribution.ArgoCDApp, _, _ = strings.Cut(trackingID, ":")
+	} else if instance := labels[argoInstanceLabel]; instance != "" {
+		attribution.ArgoCDApp = instance
+	}
+
+	if release := annotations[helmReleaseNameAnnotation]; release != "" {
+		attribution.HelmRelease = release
+		if ns := annotations[helmReleaseNSAnnotation]; ns != "" {
+			attribution.HelmRelease = ns + "/" + release
+		}
+	} else if strings.EqualFold(labels[managedByLabel], "Helm") {
+		attribution.HelmRelease = labels[appInstanceLabel]
+	}
+	attribution.HelmChart = labels[helmChartLabel]
+	return attribution
+}
+
+// changedManagers 는 이전과 비교해 시간이나 필드가 바뀐 managedFields 항목의 manager 를 찾는다.
+// 같은 시각(초 단위)에 다시 적용되어 차이가 보이지 않으면 가장 최근 manager 를 보여준다
+func changedManagers(oldEntries, newEntries []metaV1.ManagedFieldsEntry) []string {
+	changed, latest := changedEntries(oldEntries, newEntries)
+	var managers []string
+	for _, entry := range changed {
+		managers = append(managers, describeManager(entry))
+	}
+	if len(managers) == 0 && latest != nil {
+		managers = append(managers, describeManager(*latest))
+	}
+	return managers
+}
+
+// changedEntries 는 시간이나 필드가 바뀐 managedFields 항목과 가장 최근 항목을 반환한다.
+// status 서브리소스는 컨트롤러가 계속 갱신하므로 제외한다
+func changedEntries(oldEntries, newEntries []metaV1.ManagedFieldsEntry) ([]metaV1.ManagedFieldsEntry, *metaV1.ManagedFieldsEntry) {
+	entryKey := func(e metaV1.ManagedFieldsEntry) string {
+		return e.Manager + "|" + string(e.Operation) + "|" + e.Subresource
+	}
+	previous := make(map[string]metaV1.ManagedFieldsEntry, len(oldEntries))
+	for _, entry := range oldEntries {
+		previous[entryKey(entry)] = entry
+	}
+
+	var changed []metaV1.ManagedFieldsEntry
+	var latest *metaV1.ManagedFieldsEntry
+	for i, entry := range newEntries {
+		if entry.Subresource == "status" {
+			continue
+		}
+		if latest == nil || (entry.Time != nil && latest.Time != nil && latest.Time.Before(entry.Time)) {
+			latest = &newEntries[i]
+		}
+		if old, ok := previous[entryKey(entry)]; ok && reflect.DeepEqual(old.Time, entry.Time) && reflect.DeepEqual(old.FieldsV1, entry.FieldsV1) {
+			continue
+		}
+		changed = append(changed, entry)
+	}
+	return changed, latest
+}
+
+func describeManager(entry metaV1.ManagedFieldsEntry) string {
+	value := fmt.Sprintf("%s (%s)", entry.Manager, entry.Operation)
+	if entry.Subresource != "" {
+		value += " via " + entry.Subresource
+	}
+	return value
+}
+
+// lines 는 알림 본문에 붙일 내용을 만든다
+func (a attributionType) lines() []string {
+	var lines []string
+	if len(a.Managers) > 0 {
+		lines = append(lines, fmt.Sprintf("*Changed by:* `%s`", strings.Join(a.Managers, "`, `")))
+	}
+	if a.ChangeCause != "" {
+		lines = append(lines, fmt.Sprintf("*Change cause:* %s", a.ChangeCause))
+	}
+	if a.ArgoCDApp != "" {
+		lines = append(lines, fmt.Sprintf("*Argo CD app:* `%s`", a.ArgoCDApp))
+	}
+	if a.HelmRelease != "" || a.HelmChart != "" {
+		line := fmt.Sprintf("*Helm release:* `%s`", a.HelmRelease)
+		if a.HelmChart != "" {
+			line += fmt.Sprintf(" (chart `%s`)", a.HelmChart)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Summary 는 이력에 남길 한 줄 요약
+func (a attributionType) Summary() string {
+	parts := append([]string{}, a.Managers...)
+	if a.ArgoCDApp != "" {
+		parts = append(parts, "argocd:"+a.ArgoCDApp)
+	}
+	if a.HelmRelease != "" {
+		parts = append(parts, "helm:"+a.HelmRelease)
+	}
+	return strings.Join(parts, ", ")
+}
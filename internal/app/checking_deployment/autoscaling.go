@@ -0,0 +1,61 @@
+package checking_deployment
+
+import (
+	appV1 "k8s.io/api/apps/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	autoscalingListers "k8s.io/client-go/listers/autoscaling/v2"
+)
+
+// HPA(KEDA 가 만든 HPA 포함)는 kube-controller-manager 가 scale 서브리소스로 replicas 를 바꾼다
+const (
+	autoscalerManager     = "kube-controller-manager"
+	autoscalerSubresource = "scale"
+)
+
+// autoscalerIndex 는 HPA 가 replicas 를 관리하는 워크로드를 찾는다. lister 가 없으면 managedFields 로만 판단한다
+type autoscalerIndex struct {
+	hpas autoscalingListers.HorizontalPodAutoscalerLister
+}
+
+// manages 는 HPA 가 대상으로 삼는 Deployment/StatefulSet 이거나, 이번 변경이 HPA 컨트롤러의 scale 갱신뿐이면 true
+func (a autoscalerIndex) manages(oldObj, newObj metaV1.Object) bool {
+	var kind string
+	switch newObj.(type) {
+	case *appV1.Deployment:
+		kind = KindDeployment
+	case *appV1.StatefulSet:
+		kind = KindStatefulSet
+	default:
+		return false
+	}
+	if scaledByAutoscaler(oldObj.GetManagedFields(), newObj.GetManagedFields()) {
+		return true
+	}
+	if a.hpas == nil {
+		return false
+	}
+	hpas, err := a.hpas.HorizontalPodAutoscalers(newObj.GetNamespace()).List(labels.Everything())
+	if err != nil {
+		return false
+	}
+	for _, hpa := range hpas {
+		if hpa.Spec.ScaleTargetRef.Kind == kind && hpa.Spec.ScaleTargetRef.Name == newObj.GetName() {
+			return true
+		}
+	}
+	return false
+}
+
+func scaledByAutoscaler(oldEntries, newEntries []metaV1.ManagedFieldsEntry) bool {
+	changed, _ := changedEntries(oldEntries, newEntries)
+	if len(changed) == 0 {
+		return false
+	}
+	for _, entry := range changed {
+		if entry.Manager != autoscalerManager || entry.Subresource != autoscalerSubresource {
+			return false
+		}
+	}
+	return true
+}
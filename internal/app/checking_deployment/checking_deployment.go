@@ -1,130 +1,145 @@
 package checking_deployment
 
 import (
-	"bytes"
-	"encoding/json"
+	"client-go/internal/pkg/notifier"
+	"context"
 	"fmt"
-	"net/http"
-	"os"
 	"reflect"
 	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
-	appV1 "k8s.io/api/apps/v1"
 	coreV1 "k8s.io/api/core/v1"
-	"k8s.io/client-go/informers"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/cache"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// DeploymentContainer 는 변경 이력 한 건. 이미지가 바뀐 컨테이너마다 한 건씩 남기고,
+// 이미지 변경이 없는 업데이트는 ContainerName 없이 한 건으로 남긴다. Actor 는 변경한 field manager 와 Argo CD/Helm 정보 요약.
 type DeploymentContainer struct {
-	DeploymentName        string
+	Kind                  string
+	WorkloadName          string
+	ContainerName         string
 	OldContainerImageName string
 	NewContainerImageName string
 	Namespace             string
+	ChangeTypes           []string
+	Changes               string
+	PolicyViolations      []string
+	Actor                 string
 	UpdatedTime           time.Time
 }
 
-func sendSlackNotification(message, slackWebhookURL string) error {
-	payload := map[string]interface{}{
-		"text": "Deployment Update Notification",
-		"blocks": []map[string]interface{}{
-			{
-				"type": "section",
-				"text": map[string]string{
-					"type": "mrkdwn",
-					"text": message,
-				},
-			},
-		},
-	}
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return err
+// route 별 필터에서 사용하는 변경 종류
+const (
+	ChangeImage     = "image"
+	ChangeResources = "resources"
+	ChangeLabels    = "labels"
+	ChangeReplicas  = "replicas"
+	ChangeSpec      = "spec"
+	ChangeData      = "data"
+	ChangeCreated   = "created"
+	ChangeDeleted   = "deleted"
+)
+
+// changeSet 은 알림 본문 줄과 변경 종류, 이미지 변경 내역을 함께 모은다
+type changeSet struct {
+	lines  []string
+	types  []string
+	images []imageChangeType
+}
+
+// imageChangeType 은 컨테이너 하나의 이미지 변경. 추가된 컨테이너는 Old 가, 삭제된 컨테이너는 New 가 비어 있다
+type imageChangeType struct {
+	Container string
+	Old       string
+	New       string
+}
+
+func (c *changeSet) add(changeType string, lines ...string) {
+	if len(lines) == 0 {
+		return
 	}
+	c.lines = append(c.lines, lines...)
+	c.addType(changeType)
+}
 
-	resp, err := http.Post(slackWebhookURL, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return err
+// merge 는 other 의 변경 내역을 header 아래에 붙인다. header 가 비어 있으면 그대로 붙인다
+func (c *changeSet) merge(header string, other changeSet) {
+	if other.IsEmpty() {
+		return
+	}
+	if header != "" {
+		c.lines = append(c.lines, header)
+	}
+	c.lines = append(c.lines, other.lines...)
+	c.images = append(c.images, other.images...)
+	for _, t := range other.types {
+		c.addType(t)
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("slack notification returned status code %d", resp.StatusCode)
+func (c *changeSet) addType(changeType string) {
+	for _, t := range c.types {
+		if t == changeType {
+			return
+		}
 	}
+	c.types = append(c.types, changeType)
+}
 
-	return nil
+func (c *changeSet) IsEmpty() bool {
+	return len(c.lines) == 0
 }
 
-func formatChanges(oldDep, newDep *appV1.Deployment) string {
-	var changes []string
-	if oldDep.Name != newDep.Name {
-		changes = append(changes, fmt.Sprintf("- Name changed from `%s` to `%s`", oldDep.Name, newDep.Name))
+func (c *changeSet) String() string {
+	return strings.Join(c.lines, "\n")
+}
+
+// workloadState 는 pod template 을 가진 워크로드에서 비교할 값만 모은 것
+type workloadState struct {
+	Meta     metaV1.ObjectMeta
+	Replicas *int32
+	Strategy string
+	Template coreV1.PodTemplateSpec
+}
+
+// formatWorkloadChanges 는 pod template 을 가진 워크로드의 변경 내역을 만든다.
+// autoscaled 면 HPA 가 계속 바꾸는 replicas 는 비교하지 않는다
+func formatWorkloadChanges(oldState, newState workloadState, autoscaled bool) changeSet {
+	var changes changeSet
+	if oldReplicas, newReplicas := describeReplicas(oldState.Replicas), describeReplicas(newState.Replicas); !autoscaled && oldReplicas != newReplicas {
+		changes.add(ChangeReplicas, fmt.Sprintf("- Replicas changed from `%s` to `%s`", oldReplicas, newReplicas))
+	}
+	if oldState.Strategy != newState.Strategy {
+		changes.add(ChangeSpec, fmt.Sprintf("- Strategy changed from `%s` to `%s`", oldState.Strategy, newState.Strategy))
+	}
+	// old 와 new 의 pod 스펙이 동등한지 체크
+	if !reflect.DeepEqual(oldState.Template.Spec, newState.Template.Spec) {
+		changes.merge("", detectPodSpecChanges(oldState.Template.Spec, newState.Template.Spec))
 	}
-	// oldDep 와 newDep 객체가 동등한지 체크
-	if !reflect.DeepEqual(oldDep.Spec.Template.Spec.Containers, newDep.Spec.Template.Spec.Containers) {
-		changes = append(changes, "- Container specs have changed:")
-		changes = append(changes, detectContainerChanges(oldDep.Spec.Template.Spec.Containers, newDep.Spec.Template.Spec.Containers)...)
-		//changes = append(changes, detectDetailedChanges(oldDep, newDep)...)
+	// kubectl rollout restart 처럼 template annotation 만 바꿔서 재배포하는 경우
+	if annotations := detectDataChanges(oldState.Template.Annotations, newState.Template.Annotations, inlineValue); len(annotations) > 0 {
+		changes.add(ChangeSpec, append([]string{"- Pod template annotation changes:"}, annotations...)...)
 	}
-	labelChanges := detectLabelChanges(oldDep.Labels, newDep.Labels)
+	// pod template label 은 selector 와 Service, NetworkPolicy 대상에 영향을 준다
+	if templateLabels := detectLabelChanges(oldState.Template.Labels, newState.Template.Labels); len(templateLabels) > 0 {
+		changes.add(ChangeLabels, append([]string{"- Pod template label changes:"}, templateLabels...)...)
+	}
+	labelChanges := detectLabelChanges(oldState.Meta.Labels, newState.Meta.Labels)
 	if len(labelChanges) > 0 {
-		changes = append(changes, "- Label changes:")
-		changes = append(changes, labelChanges...)
-	}
-	return strings.Join(changes, "\n")
-}
-
-//func detectDetailedChanges(oldDep, newDep *appV1.Deployment) []string {
-//	var changes []string
-//
-//	// 컨테이너 수 비교
-//	if len(oldDep.Spec.Template.Spec.Containers) != len(newDep.Spec.Template.Spec.Containers) {
-//		changes = append(changes, fmt.Sprintf("Number of containers changed from `%d` to `%d`",
-//			len(oldDep.Spec.Template.Spec.Containers), len(newDep.Spec.Template.Spec.Containers)))
-//	}
-//
-//	// 컨테이너별 세부 비교
-//	for i, oldContainer := range oldDep.Spec.Template.Spec.Containers {
-//		if i >= len(newDep.Spec.Template.Spec.Containers) {
-//			continue
-//		}
-//		newContainer := newDep.Spec.Template.Spec.Containers[i]
-//
-//		if oldContainer.Image != newContainer.Image {
-//			changes = append(changes, fmt.Sprintf("Container `%s` image changed from `%s` to `%s`",
-//				oldContainer.Name, oldContainer.Image, newContainer.Image))
-//		}
-//
-//		if !reflect.DeepEqual(oldContainer.Resources, newContainer.Resources) {
-//			changes = append(changes, fmt.Sprintf("Resources for container `%s` changed", oldContainer.Name))
-//		}
-//
-//		// 다른 필드들도 비슷하게 추가 가능
-//	}
-//
-//	return changes
-//}
-
-func detectContainerChanges(oldContainers, newContainers []coreV1.Container) []string {
-	var changes []string
-	for i, newContainer := range newContainers {
-		if i < len(oldContainers) {
-			oldContainer := oldContainers[i]
-			if oldContainer.Image != newContainer.Image {
-				changes = append(changes, fmt.Sprintf("    - Image changed from `%s` to `%s`", oldContainer.Image, newContainer.Image))
-			}
-			if !reflect.DeepEqual(oldContainer.Resources, newContainer.Resources) {
-				changes = append(changes, fmt.Sprintf("    - Resources changed for container `%s`", newContainer.Name))
-			}
-		} else {
-			changes = append(changes, fmt.Sprintf("    - New container added: `%s`", newContainer.Name))
-		}
+		changes.add(ChangeLabels, append([]string{"- Label changes:"}, labelChanges...)...)
 	}
 	return changes
 }
 
+func describeReplicas(replicas *int32) string {
+	if replicas == nil {
+		return "default"
+	}
+	return fmt.Sprintf("%d", *replicas)
+}
+
 func detectLabelChanges(oldLabels, newLabels map[string]string) []string {
 	var changes []string
 	// 새로 추가된 레이블 검사
@@ -144,31 +159,127 @@ func detectLabelChanges(oldLabels, newLabels map[string]string) []string {
 	return changes
 }
 
-func handleDeploymentUpdate(oldObj, newObj interface{}) {
-	oldDep, _ := oldObj.(*appV1.Deployment)
-	newDep, _ := newObj.(*appV1.Deployment)
+// ignoreUpdate 는 resync(같은 ResourceVersion) 와 status 만 바뀐 업데이트(같은 generation 에 label 도 그대로)를 걸러낸다.
+// ConfigMap, Secret 처럼 generation 을 쓰지 않는 오브젝트는 ResourceVersion 으로만 판단한다
+func ignoreUpdate(oldObj, newObj interface{}) bool {
+	oldMeta, err := meta.Accessor(oldObj)
+	if err != nil {
+		return false
+	}
+	newMeta, err := meta.Accessor(newObj)
+	if err != nil {
+		return false
+	}
+	if oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+		return true
+	}
+	return newMeta.GetGeneration() > 0 && oldMeta.GetGeneration() == newMeta.GetGeneration() &&
+		reflect.DeepEqual(oldMeta.GetLabels(), newMeta.GetLabels())
+}
 
-	webhookURL := os.Getenv("SLACK_WEBHOOK_URL")
-	message := formatChanges(oldDep, newDep)
-	if message != "" {
-		message = fmt.Sprintf("*Updated Deployment:* `%s`\n%s", newDep.Name, message)
-		if err := sendSlackNotification(message, webhookURL); err != nil {
-			log.WithError(err).Error("Failed to send Slack notification")
-		}
+// handleUpdate 는 감시 중인 오브젝트의 변경 내역을 만들어 publish 한다
+func (w *Watcher) handleUpdate(oldObj, newObj interface{}, autoscalers autoscalerIndex) {
+	if ignoreUpdate(oldObj, newObj) {
+		return
 	}
+	oldMeta, err := meta.Accessor(oldObj)
+	if err != nil {
+		return
+	}
+	obj, err := meta.Accessor(newObj)
+	if err != nil {
+		return
+	}
+	kind, changes := describeChanges(oldObj, newObj, autoscalers.manages(oldMeta, obj))
+	if changes.IsEmpty() {
+		return
+	}
+	w.publish(eventUpdated, kind, obj, changes, attributeChange(oldMeta, obj))
 }
 
-func CheckingContainerImage(clientSet *kubernetes.Clientset) {
-	factory := informers.NewSharedInformerFactory(clientSet, time.Second*30)
-	informer := factory.Apps().V1().Deployments().Informer()
+// 알림 event 이름과 제목에 쓰는 변경 동작
+type changeEventType struct {
+	Verb  string
+	Past  string
+	Title string
+}
 
-	stopCh := make(chan struct{})
-	defer close(stopCh)
+var (
+	eventCreated = changeEventType{Verb: "created", Past: "Created", Title: "Create"}
+	eventUpdated = changeEventType{Verb: "updated", Past: "Updated", Title: "Update"}
+	eventDeleted = changeEventType{Verb: "deleted", Past: "Deleted", Title: "Delete"}
+)
 
-	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		UpdateFunc: handleDeploymentUpdate,
-	})
+// changeNotificationType 은 변경 알림 템플릿에 Message.Data 로 넘기는 값
+type changeNotificationType struct {
+	// Action 은 Created, Updated, Deleted 중 하나
+	Action    string
+	Kind      string
+	Namespace string
+	Name      string
+	Actor     string
+	// Attribution 은 변경 주체를 설명하는 markdown 줄
+	Attribution []string
+	ChangeTypes []string
+	Changes     []string
+	Images      []imageChangeType
+	Violations  []policyViolationType
+}
 
-	go informer.Run(stopCh)
-	<-stopCh
+// publish 는 변경 한 건을 이력에 남기고, 새 이미지를 정책에 비춰본 뒤 알림을 보낸다
+func (w *Watcher) publish(event changeEventType, kind string, obj metaV1.Object, changes changeSet, attribution attributionType) {
+	header := strings.Join(append([]string{fmt.Sprintf("`%s/%s`", obj.GetNamespace(), obj.GetName())}, attribution.lines()...), "\n")
+
+	violations := w.options.ImagePolicy.Evaluate(obj.GetNamespace(), changes.images)
+	data := changeNotificationType{
+		Action:      event.Past,
+		Kind:        kind,
+		Namespace:   obj.GetNamespace(),
+		Name:        obj.GetName(),
+		Actor:       attribution.Summary(),
+		Attribution: attribution.lines(),
+		ChangeTypes: changes.types,
+		Changes:     changes.lines,
+		Images:      changes.images,
+		Violations:  violations,
+	}
+	records := historyRecords(kind, obj.GetNamespace(), obj.GetName(), changes, attribution.Summary(), time.Now())
+	attachViolations(records, violations)
+	if err := w.options.History.Record(records...); err != nil {
+		log.WithError(err).Error("Failed to record change history")
+	}
+
+	if len(violations) > 0 {
+		err := w.notifier.Notify(context.Background(), notifier.Message{
+			Event:       "image.policy.violation",
+			Title:       "Image Policy Violation",
+			Text:        fmt.Sprintf("*Image policy violated by %s:* %s\n%s", kind, header, formatViolations(violations)),
+			Severity:    notifier.SeverityCritical,
+			Namespace:   obj.GetNamespace(),
+			Labels:      obj.GetLabels(),
+			ChangeTypes: changes.types,
+			Data:        data,
+		})
+		if err != nil {
+			log.WithError(err).Error("Failed to send image policy notification")
+		}
+	}
+
+	severity := notifier.SeverityInfo
+	if event == eventDeleted {
+		severity = notifier.SeverityWarning
+	}
+	err := w.notifier.Notify(context.Background(), notifier.Message{
+		Event:       strings.ToLower(kind) + "." + event.Verb,
+		Title:       kind + " " + event.Title + " Notification",
+		Text:        fmt.Sprintf("*%s %s:* %s\n%s", event.Past, kind, header, changes.String()),
+		Severity:    severity,
+		Namespace:   obj.GetNamespace(),
+		Labels:      obj.GetLabels(),
+		ChangeTypes: changes.types,
+		Data:        data,
+	})
+	if err != nil {
+		log.WithError(err).Errorf("Failed to send %s %s notification", strings.ToLower(kind), event.Verb)
+	}
 }
@@ -0,0 +1,80 @@
+package checking_deployment
+
+import (
+	"client-go/internal/app/pod_metadata"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// helm release 와 service account token 은 자주 바뀌고 알릴 가치가 없어 기본으로 보지 않는다
+var defaultSkippedSecretTypes = []string{"helm.sh/release.v1", string(coreV1.SecretTypeServiceAccountToken)}
+
+// ConfigWatchOptions 는 ConfigMap, Secret 변경 감지 설정. 클러스터 전체의 Secret 을 캐시하지 않도록 기본으로 꺼져 있다
+type ConfigWatchOptions struct {
+	Enabled bool
+	// Namespace 가 비어 있으면 모든 namespace 를 본다
+	Namespace     string
+	LabelSelector string
+	// SkippedSecretTypes 에 있는 type 의 Secret 은 API 서버에서 걸러서 받지 않는다
+	SkippedSecretTypes []string
+}
+
+// ConfigWatchOptionsFromEnv 는 WATCH_CONFIG_CHANGES(기본 false), WATCH_CONFIG_NAMESPACE,
+// WATCH_CONFIG_LABEL_SELECTOR, WATCH_SECRET_SKIP_TYPES(쉼표 구분, 기본 helm release 와 service account token) 로 옵션을 만든다
+func ConfigWatchOptionsFromEnv() (ConfigWatchOptions, error) {
+	opts := ConfigWatchOptions{
+		Enabled:            os.Getenv("WATCH_CONFIG_CHANGES") == "true",
+		Namespace:          os.Getenv("WATCH_CONFIG_NAMESPACE"),
+		LabelSelector:      os.Getenv("WATCH_CONFIG_LABEL_SELECTOR"),
+		SkippedSecretTypes: defaultSkippedSecretTypes,
+	}
+	if value, ok := os.LookupEnv("WATCH_SECRET_SKIP_TYPES"); ok {
+		opts.SkippedSecretTypes = pod_metadata.SplitList(value)
+	}
+	if _, err := labels.Parse(opts.LabelSelector); err != nil {
+		return opts, fmt.Errorf("invalid WATCH_CONFIG_LABEL_SELECTOR %q: %w", opts.LabelSelector, err)
+	}
+	return opts, nil
+}
+
+// secretFieldSelector 는 SkippedSecretTypes 를 제외하는 field selector
+func (o ConfigWatchOptions) secretFieldSelector() string {
+	selectors := make([]string, 0, len(o.SkippedSecretTypes))
+	for _, secretType := range o.SkippedSecretTypes {
+		selectors = append(selectors, "type!="+secretType)
+	}
+	return strings.Join(selectors, ",")
+}
+
+// configInformers 는 Namespace 와 LabelSelector 로 범위를 좁힌 ConfigMap, Secret informer 를 만든다.
+// field selector 는 Secret 에만 쓸 수 있으므로 Secret informer 는 따로 만든다
+func configInformers(clientSet kubernetes.Interface, resync time.Duration, options ConfigWatchOptions) (informers.SharedInformerFactory, map[string]cache.SharedIndexInformer) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientSet, resync,
+		informers.WithNamespace(options.Namespace),
+		informers.WithTweakListOptions(func(list *metav1.ListOptions) {
+			list.LabelSelector = options.LabelSelector
+		}),
+	)
+	secrets := factory.InformerFor(&coreV1.Secret{}, func(clientSet kubernetes.Interface, resync time.Duration) cache.SharedIndexInformer {
+		return coreinformers.NewFilteredSecretInformer(clientSet, options.Namespace, resync,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+			func(list *metav1.ListOptions) {
+				list.LabelSelector = options.LabelSelector
+				list.FieldSelector = options.secretFieldSelector()
+			})
+	})
+	return factory, map[string]cache.SharedIndexInformer{
+		KindConfigMap: factory.Core().V1().ConfigMaps().Informer(),
+		KindSecret:    secrets,
+	}
+}
@@ -0,0 +1,247 @@
+package checking_deployment
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDataDir           = "./data"
+	defaultHistoryFileName   = "deployment-changes.jsonl"
+	defaultHistoryLimit      = 100
+	defaultHistoryMaxSizeMB  = 10
+	defaultHistoryMaxBackups = 3
+)
+
+// History 는 감지한 변경을 JSON lines 파일에 이어 쓰는 변경 이력 저장소.
+// 파일이 maxSize 를 넘으면 <path>.1, <path>.2 ... 로 밀어내고 maxBackups 개까지만 남긴다
+type History struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	mu         sync.RWMutex
+}
+
+// HistoryFilter 는 변경 이력 조회 조건. 비어 있는 값은 조건으로 쓰지 않는다
+type HistoryFilter struct {
+	Namespace string
+	Workload  string
+	Kind      string
+	Container string
+	// Image 는 변경 전후 이미지 어느 쪽에든 포함되면 맞는 것으로 본다
+	Image string
+	// Actor 는 field manager, Argo CD app, Helm release 요약에 포함되면 맞는 것으로 본다
+	Actor string
+	// ViolationsOnly 면 이미지 정책 위반이 있는 레코드만 반환
+	ViolationsOnly bool
+	Since          time.Time
+	Until          time.Time
+	Limit          int
+}
+
+// NewHistoryFromEnv 는 DEPLOYMENT_HISTORY_FILE(기본 <DATA_DIR>/deployment-changes.jsonl, DATA_DIR 기본 ./data) 에 이력을 남기는 History 를 만든다.
+// DEPLOYMENT_HISTORY_MAX_SIZE_MB(기본 10), DEPLOYMENT_HISTORY_MAX_BACKUPS(기본 3) 로 보관량을 정한다
+func NewHistoryFromEnv() *History {
+	path := os.Getenv("DEPLOYMENT_HISTORY_FILE")
+	if path == "" {
+		dataDir := os.Getenv("DATA_DIR")
+		if dataDir == "" {
+			dataDir = defaultDataDir
+		}
+		path = filepath.Join(dataDir, defaultHistoryFileName)
+	}
+	history := &History{path: path, maxSize: defaultHistoryMaxSizeMB << 20, maxBackups: defaultHistoryMaxBackups}
+	if size, err := strconv.ParseInt(os.Getenv("DEPLOYMENT_HISTORY_MAX_SIZE_MB"), 10, 64); err == nil && size > 0 {
+		history.maxSize = size << 20
+	}
+	if backups, err := strconv.Atoi(os.Getenv("DEPLOYMENT_HISTORY_MAX_BACKUPS")); err == nil && backups >= 0 {
+		history.maxBackups = backups
+	}
+	return history
+}
+
+func (h *History) Record(records ...DeploymentContainer) error {
+	if len(records) == 0 {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return err
+	}
+	if err := h.rotate(); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rotate 는 현재 파일이 maxSize 를 넘었으면 백업 파일로 밀어낸다. 가장 오래된 백업은 지운다
+func (h *History) rotate() error {
+	info, err := os.Stat(h.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if h.maxSize <= 0 || info.Size() < h.maxSize {
+		return nil
+	}
+	if h.maxBackups == 0 {
+		return os.Remove(h.path)
+	}
+	if err := os.Remove(h.backupPath(h.maxBackups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := h.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(h.backupPath(i), h.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(h.path, h.backupPath(1))
+}
+
+func (h *History) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", h.path, n)
+}
+
+// Query 는 조건에 맞는 이력을 최신순으로 최대 Limit(기본 100) 건 반환. 백업 파일까지 읽는다
+func (h *History) Query(filter HistoryFilter) ([]DeploymentContainer, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultHistoryLimit
+	}
+	// 읽는 동안 Record 가 rotate 하지 않도록 read lock 을 잡는다. 조회끼리는 서로 막지 않는다
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	records := []DeploymentContainer{}
+	paths := []string{h.path}
+	for i := 1; i <= h.maxBackups; i++ {
+		paths = append(paths, h.backupPath(i))
+	}
+	for _, path := range paths {
+		var err error
+		if records, err = scanHistory(path, filter, records); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].UpdatedTime.After(records[j].UpdatedTime)
+	})
+	if len(records) > filter.Limit {
+		records = records[:filter.Limit]
+	}
+	return records, nil
+}
+
+// scanHistory 는 파일 하나에서 조건에 맞는 레코드를 records 에 덧붙인다. 파일이 없으면 그대로 반환
+func scanHistory(path string, filter HistoryFilter, records []DeploymentContainer) ([]DeploymentContainer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return records, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var record DeploymentContainer
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// 쓰다가 끊긴 줄은 건너뛴다
+			continue
+		}
+		if filter.matches(record) {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (f HistoryFilter) matches(record DeploymentContainer) bool {
+	switch {
+	case f.Namespace != "" && record.Namespace != f.Namespace:
+		return false
+	case f.Workload != "" && record.WorkloadName != f.Workload:
+		return false
+	case f.Kind != "" && !strings.EqualFold(record.Kind, f.Kind):
+		return false
+	case f.Container != "" && record.ContainerName != f.Container:
+		return false
+	case f.Image != "" && !strings.Contains(record.OldContainerImageName, f.Image) && !strings.Contains(record.NewContainerImageName, f.Image):
+		return false
+	case f.Actor != "" && !strings.Contains(record.Actor, f.Actor):
+		return false
+	case f.ViolationsOnly && len(record.PolicyViolations) == 0:
+		return false
+	case !f.Since.IsZero() && record.UpdatedTime.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && record.UpdatedTime.After(f.Until):
+		return false
+	}
+	return true
+}
+
+// historyRecords 는 변경 한 건을 이력 레코드로 바꾼다
+func historyRecords(kind, namespace, name string, changes changeSet, actor string, at time.Time) []DeploymentContainer {
+	base := DeploymentContainer{
+		Kind:         kind,
+		Actor:        actor,
+		WorkloadName: name,
+		Namespace:    namespace,
+		ChangeTypes:  changes.types,
+		Changes:      changes.String(),
+		UpdatedTime:  at,
+	}
+	if len(changes.images) == 0 {
+		return []DeploymentContainer{base}
+	}
+
+	records := make([]DeploymentContainer, 0, len(changes.images))
+	for _, image := range changes.images {
+		record := base
+		record.ContainerName = image.Container
+		record.OldContainerImageName = image.Old
+		record.NewContainerImageName = image.New
+		records = append(records, record)
+	}
+	return records
+}
+
+// attachViolations 는 정책 위반을 해당 컨테이너의 이력 레코드에 붙인다
+func attachViolations(records []DeploymentContainer, violations []policyViolationType) {
+	for i := range records {
+		for _, v := range violations {
+			if records[i].ContainerName == v.Container {
+				records[i].PolicyViolations = append(records[i].PolicyViolations, v.Rule+": "+v.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package checking_deployment
+
+import (
+	"client-go/internal/app/pod_metadata"
+	"client-go/internal/pkg/image"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	PolicyDisallowedRegistry = "disallowed-registry"
+	PolicyFloatingTag        = "floating-tag"
+	PolicyUnpinnedDigest     = "unpinned-digest"
+	PolicyMajorVersionJump   = "major-version-jump"
+)
+
+// ImagePolicy 는 IMAGE_POLICY_FILE(JSON) 로 덮어쓸 수 있는 이미지 정책
+//
+//	{
+//	  "AllowedRegistries": ["123456789012.dkr.ecr.ap-northeast-2.amazonaws.com", "ghcr.io/my-org"],
+//	  "ForbidFloatingTags": true,
+//	  "RequireDigestInProduction": true,
+//	  "ProductionScope": "product",
+//	  "FlagMajorVersionJumps": true
+//	}
+type ImagePolicy struct {
+	// AllowedRegistries 가 비어 있으면 registry 는 검사하지 않는다. "registry/경로" 처럼 경로 prefix 까지 지정할 수 있다
+	AllowedRegistries         []string
+	ForbidFloatingTags        bool
+	RequireDigestInProduction bool
+	// ProductionScope 는 digest 고정을 요구할 namespace scope preset 이름
+	ProductionScope       string
+	FlagMajorVersionJumps bool
+}
+
+type policyViolationType struct {
+	Rule      string
+	Container string
+	Image     string
+	Message   string
+}
+
+func defaultImagePolicy() ImagePolicy {
+	return ImagePolicy{
+		ForbidFloatingTags:        true,
+		RequireDigestInProduction: true,
+		ProductionScope:           "product",
+		FlagMajorVersionJumps:     true,
+	}
+}
+
+// ImagePolicyFromEnv 는 IMAGE_POLICY_FILE 이 있으면 그 내용을, 없으면 기본 정책을 반환
+func ImagePolicyFromEnv() (ImagePolicy, error) {
+	policy := defaultImagePolicy()
+	path := os.Getenv("IMAGE_POLICY_FILE")
+	if path == "" {
+		return policy, policy.Validate()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policy, fmt.Errorf("could not read image policy file: %w", err)
+	}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return policy, fmt.Errorf("invalid image policy file %s: %w", path, err)
+	}
+	return policy, policy.Validate()
+}
+
+func (p ImagePolicy) Validate() error {
+	if !p.RequireDigestInProduction {
+		return nil
+	}
+	_, err := p.productionMatcher()
+	return err
+}
+
+func (p ImagePolicy) productionMatcher() (func(string) bool, error) {
+	scope, err := pod_metadata.ScopePreset(p.ProductionScope)
+	if err != nil {
+		return nil, err
+	}
+	return scope.NameMatcher()
+}
+
+// Evaluate 는 바뀐 이미지들을 정책에 비춰보고 위반 사항을 반환한다. 삭제된 컨테이너는 검사하지 않는다
+func (p ImagePolicy) Evaluate(namespace string, images []imageChangeType) []policyViolationType {
+	production := false
+	if p.RequireDigestInProduction {
+		if isProduction, err := p.productionMatcher(); err == nil {
+			production = isProduction(namespace)
+		}
+	}
+
+	var violations []policyViolationType
+	for _, change := range images {
+		if change.New == "" {
+			continue
+		}
+		ref := image.Parse(change.New)
+		report := func(rule, format string, args ...interface{}) {
+			violations = append(violations, policyViolationType{
+				Rule:      rule,
+				Container: change.Container,
+				Image:     change.New,
+				Message:   fmt.Sprintf(format, args...),
+			})
+		}
+
+		if len(p.AllowedRegistries) > 0 && !p.registryAllowed(ref) {
+			report(PolicyDisallowedRegistry, "registry %s is not in the allowed list", ref.Registry)
+		}
+		if p.ForbidFloatingTags && ref.IsFloating() {
+			report(PolicyFloatingTag, "image is untagged or uses :latest")
+		}
+		if production && !ref.IsPinned() {
+			report(PolicyUnpinnedDigest, "image is not pinned by digest in production namespace %s", namespace)
+		}
+		if p.FlagMajorVersionJumps && change.Old != "" {
+			oldVersion, oldOk := image.Parse(change.Old).Version()
+			newVersion, newOk := ref.Version()
+			if oldOk && newOk && oldVersion.Major != newVersion.Major {
+				report(PolicyMajorVersionJump, "major version changed from %d to %d", oldVersion.Major, newVersion.Major)
+			}
+		}
+	}
+	return violations
+}
+
+func (p ImagePolicy) registryAllowed(ref image.Reference) bool {
+	full := ref.Registry + "/" + ref.Repository
+	for _, allowed := range p.AllowedRegistries {
+		allowed = strings.TrimSuffix(allowed, "/")
+		if full == allowed || ref.Registry == allowed || strings.HasPrefix(full, allowed+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func formatViolations(violations []policyViolationType) string {
+	lines := make([]string, 0, len(violations))
+	for _, v := range violations {
+		lines = append(lines, fmt.Sprintf("- [%s] container `%s` image `%s`: %s", v.Rule, v.Container, v.Image, v.Message))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,43 @@
+package checking_deployment
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestImagePolicyEvaluate(t *testing.T) {
+	policy := ImagePolicy{
+		AllowedRegistries:     []string{"ghcr.io/my-org", "registry.local:5000"},
+		ForbidFloatingTags:    true,
+		FlagMajorVersionJumps: true,
+	}
+	tests := []struct {
+		name     string
+		old, new string
+		want     []string
+	}{
+		{"minor bump", "ghcr.io/my-org/app:1.2.3", "ghcr.io/my-org/app:1.3.0", nil},
+		{"major bump", "ghcr.io/my-org/app:1.9.0", "ghcr.io/my-org/app:2.0.0", []string{PolicyMajorVersionJump}},
+		{"major downgrade", "ghcr.io/my-org/app:v2.1.0", "ghcr.io/my-org/app:v1.9.0", []string{PolicyMajorVersionJump}},
+		{"minor downgrade", "ghcr.io/my-org/app:1.3.0", "ghcr.io/my-org/app:1.2.9", nil},
+		{"pre-release to release", "ghcr.io/my-org/app:2.0.0-rc1", "ghcr.io/my-org/app:2.0.0", nil},
+		{"build number tags", "ghcr.io/my-org/app:1233", "ghcr.io/my-org/app:1234", nil},
+		{"v1 to v2", "ghcr.io/my-org/app:v1", "ghcr.io/my-org/app:v2", []string{PolicyMajorVersionJump}},
+		{"registry port", "registry.local:5000/app:1.0", "registry.local:5000/app:1.1", nil},
+		{"latest", "ghcr.io/my-org/app:1.0", "ghcr.io/my-org/app:latest", []string{PolicyFloatingTag}},
+		{"digest only", "ghcr.io/my-org/app:1.0", "ghcr.io/my-org/app@sha256:abc", nil},
+		{"disallowed registry", "", "docker.io/library/nginx:1.25", []string{PolicyDisallowedRegistry}},
+		{"untagged from other registry", "", "nginx", []string{PolicyDisallowedRegistry, PolicyFloatingTag}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules []string
+			for _, v := range policy.Evaluate("dev-api", []imageChangeType{{Container: "app", Old: tt.old, New: tt.new}}) {
+				rules = append(rules, v.Rule)
+			}
+			if !reflect.DeepEqual(rules, tt.want) {
+				t.Errorf("violations = %v, want %v", rules, tt.want)
+			}
+		})
+	}
+}
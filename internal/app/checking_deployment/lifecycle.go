@@ -0,0 +1,79 @@
+package checking_deployment
+
+import (
+	"fmt"
+
+	appV1 "k8s.io/api/apps/v1"
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// handleAdd 는 새로 만들어진 워크로드를 알린다. informer 초기 목록(기존 오브젝트)은 알리지 않는다
+func (w *Watcher) handleAdd(obj interface{}, isInInitialList bool) {
+	if isInInitialList {
+		return
+	}
+	kind, template, ok := workloadTemplate(obj)
+	if !ok {
+		return
+	}
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+
+	var changes changeSet
+	for _, container := range templateContainers(template) {
+		changes.add(ChangeCreated, fmt.Sprintf("- Container `%s`: `%s`", container.Name, container.Image))
+		changes.images = append(changes.images, imageChangeType{Container: container.Name, New: container.Image})
+	}
+	// 새 오브젝트이므로 모든 field manager 가 변경한 것으로 보인다
+	w.publish(eventCreated, kind, objMeta, changes, attributeChange(&metaV1.ObjectMeta{}, objMeta))
+}
+
+// handleDelete 는 삭제된 워크로드를 알린다. watch 가 끊긴 사이 삭제되면 tombstone 으로 전달된다
+func (w *Watcher) handleDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	kind, template, ok := workloadTemplate(obj)
+	if !ok {
+		return
+	}
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+
+	var changes changeSet
+	for _, container := range templateContainers(template) {
+		changes.add(ChangeDeleted, fmt.Sprintf("- Container `%s`: `%s`", container.Name, container.Image))
+		changes.images = append(changes.images, imageChangeType{Container: container.Name, Old: container.Image})
+	}
+	// 누가 삭제했는지는 오브젝트에 남지 않으므로 Argo CD/Helm 정보만 보여준다
+	attribution := attributeChange(objMeta, objMeta)
+	attribution.Managers = nil
+	w.publish(eventDeleted, kind, objMeta, changes, attribution)
+}
+
+// workloadTemplate 은 생성/삭제를 알릴 워크로드만 골라 pod template 을 반환
+func workloadTemplate(obj interface{}) (string, coreV1.PodTemplateSpec, bool) {
+	switch obj := obj.(type) {
+	case *appV1.Deployment:
+		return KindDeployment, obj.Spec.Template, true
+	case *appV1.StatefulSet:
+		return KindStatefulSet, obj.Spec.Template, true
+	case *appV1.DaemonSet:
+		return KindDaemonSet, obj.Spec.Template, true
+	case *batchV1.CronJob:
+		return KindCronJob, obj.Spec.JobTemplate.Spec.Template, true
+	}
+	return "", coreV1.PodTemplateSpec{}, false
+}
+
+func templateContainers(template coreV1.PodTemplateSpec) []coreV1.Container {
+	return append(append([]coreV1.Container{}, template.Spec.InitContainers...), template.Spec.Containers...)
+}
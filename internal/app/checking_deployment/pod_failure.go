@@ -0,0 +1,310 @@
+package checking_deployment
+
+import (
+	"client-go/internal/pkg/kube"
+	"client-go/internal/pkg/notifier"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	FailureCrashLoop = "CrashLoopBackOff"
+	FailureOOMKilled = "OOMKilled"
+	FailureImagePull = "ImagePullError"
+	FailureRestarts  = "RestartThreshold"
+
+	defaultPodFailureCooldown = 30 * time.Minute
+)
+
+var (
+	defaultRestartThresholds = []int32{5, 20}
+	imagePullReasons         = map[string]bool{
+		"ImagePullBackOff": true,
+		"ErrImagePull":     true,
+	}
+)
+
+// PodFailureOptions 는 pod 실패 알림 설정. 모든 Pod 를 캐시하므로 기본으로 꺼져 있다
+type PodFailureOptions struct {
+	Enabled bool
+	// Namespace 가 비어 있으면 모든 namespace 의 Pod 를 본다
+	Namespace string
+	// RestartThresholds 는 오름차순. 재시작 횟수가 각 값을 넘어설 때 한 번씩 알린다
+	RestartThresholds []int32
+	// Cooldown 동안 같은 워크로드의 같은 종류 실패는 다시 알리지 않고 개수만 센다
+	Cooldown time.Duration
+}
+
+// PodFailureOptionsFromEnv 는 POD_FAILURE_ALERTS(기본 false), POD_FAILURE_NAMESPACE, POD_RESTART_THRESHOLDS(기본 5,20),
+// POD_FAILURE_COOLDOWN(기본 30m) 로 pod 실패 알림 옵션을 만든다
+func PodFailureOptionsFromEnv() PodFailureOptions {
+	opts := PodFailureOptions{
+		Enabled:           os.Getenv("POD_FAILURE_ALERTS") == "true",
+		Namespace:         os.Getenv("POD_FAILURE_NAMESPACE"),
+		RestartThresholds: defaultRestartThresholds,
+		Cooldown:          defaultPodFailureCooldown,
+	}
+	if value := os.Getenv("POD_RESTART_THRESHOLDS"); value != "" {
+		var thresholds []int32
+		for _, field := range strings.Split(value, ",") {
+			if n, err := strconv.ParseInt(strings.TrimSpace(field), 10, 32); err == nil && n > 0 {
+				thresholds = append(thresholds, int32(n))
+			}
+		}
+		sort.Slice(thresholds, func(i, j int) bool { return thresholds[i] < thresholds[j] })
+		opts.RestartThresholds = thresholds
+	}
+	if cooldown, err := time.ParseDuration(os.Getenv("POD_FAILURE_COOLDOWN")); err == nil && cooldown >= 0 {
+		opts.Cooldown = cooldown
+	}
+	return opts
+}
+
+// podInformer 는 Namespace 로 범위를 좁히고 실패 감지에 쓰는 필드만 남겨 캐시하는 Pod informer 를 만든다
+func podInformer(clientSet kubernetes.Interface, resync time.Duration, options PodFailureOptions) (informers.SharedInformerFactory, cache.SharedIndexInformer) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientSet, resync,
+		informers.WithNamespace(options.Namespace),
+		informers.WithTransform(trimPod),
+	)
+	return factory, factory.Core().V1().Pods().Informer()
+}
+
+// trimPod 는 detect, send 에서 쓰는 필드만 남긴다. Pod 가 아닌 값(DeletedFinalStateUnknown 등)은 그대로 둔다
+func trimPod(obj interface{}) (interface{}, error) {
+	pod, ok := obj.(*coreV1.Pod)
+	if !ok {
+		return obj, nil
+	}
+	return &coreV1.Pod{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+			Labels:          pod.Labels,
+			OwnerReferences: pod.OwnerReferences,
+		},
+		Spec: coreV1.PodSpec{NodeName: pod.Spec.NodeName},
+		Status: coreV1.PodStatus{
+			Phase:                 pod.Status.Phase,
+			InitContainerStatuses: pod.Status.InitContainerStatuses,
+			ContainerStatuses:     pod.Status.ContainerStatuses,
+		},
+	}, nil
+}
+
+// podFailureAlertType 은 컨테이너 실패 한 건. 알림 템플릿에 Message.Data 로도 넘긴다
+type podFailureAlertType struct {
+	// Failure 는 FailureCrashLoop, FailureOOMKilled, FailureImagePull, FailureRestarts 중 하나
+	Failure      string
+	Reason       string
+	Namespace    string
+	Pod          string
+	Node         string
+	Container    string
+	OwnerKind    string
+	OwnerName    string
+	RestartCount int32
+	Message      string
+	// LastTermination 은 직전 종료 상태 요약 (reason, exit code, 시각, termination message)
+	LastTermination string
+	// Suppressed 는 지난 알림 이후 cooldown 때문에 보내지 않은 같은 워크로드의 실패 수
+	Suppressed int
+}
+
+// podFailureDetector 는 pod 상태 변화에서 컨테이너 실패를 찾아 워크로드 단위로 중복을 걸러 알린다
+type podFailureDetector struct {
+	notifier notifier.Notifier
+	options  PodFailureOptions
+
+	mu   sync.Mutex
+	sent map[string]*podAlertStateType
+}
+
+type podAlertStateType struct {
+	sentAt     time.Time
+	suppressed int
+}
+
+func newPodFailureDetector(n notifier.Notifier, options PodFailureOptions) *podFailureDetector {
+	return &podFailureDetector{notifier: n, options: options, sent: map[string]*podAlertStateType{}}
+}
+
+func (d *podFailureDetector) observe(oldObj, newObj interface{}) {
+	oldPod, ok := oldObj.(*coreV1.Pod)
+	if !ok {
+		return
+	}
+	newPod, ok := newObj.(*coreV1.Pod)
+	if !ok || oldPod.ResourceVersion == newPod.ResourceVersion {
+		return
+	}
+	for _, alert := range d.detect(oldPod, newPod) {
+		if d.shouldSend(&alert) {
+			d.send(alert, newPod.Labels)
+		}
+	}
+}
+
+// detect 는 이전 상태와 비교해 새로 생긴 실패만 찾는다
+func (d *podFailureDetector) detect(oldPod, newPod *coreV1.Pod) []podFailureAlertType {
+	previous := map[string]coreV1.ContainerStatus{}
+	for _, status := range containerStatuses(oldPod) {
+		previous[status.Name] = status
+	}
+
+	ownerKind, ownerName := kube.OwnerWorkload(newPod)
+	var alerts []podFailureAlertType
+	for _, status := range containerStatuses(newPod) {
+		old := previous[status.Name]
+		report := func(failure, reason, message string) {
+			alerts = append(alerts, podFailureAlertType{
+				Failure:         failure,
+				Reason:          reason,
+				Namespace:       newPod.Namespace,
+				Pod:             newPod.Name,
+				Node:            newPod.Spec.NodeName,
+				Container:       status.Name,
+				OwnerKind:       ownerKind,
+				OwnerName:       ownerName,
+				RestartCount:    status.RestartCount,
+				Message:         message,
+				LastTermination: describeTermination(status.LastTerminationState.Terminated),
+			})
+		}
+
+		if waiting := status.State.Waiting; waiting != nil && waitingReason(old) != waiting.Reason {
+			switch {
+			case waiting.Reason == FailureCrashLoop:
+				report(FailureCrashLoop, waiting.Reason, waiting.Message)
+			case imagePullReasons[waiting.Reason] && !imagePullReasons[waitingReason(old)]:
+				report(FailureImagePull, waiting.Reason, waiting.Message)
+			}
+		}
+
+		if terminated := newTermination(old, status); terminated != nil && terminated.Reason == FailureOOMKilled {
+			report(FailureOOMKilled, terminated.Reason, "")
+		}
+
+		for _, threshold := range d.options.RestartThresholds {
+			if old.RestartCount < threshold && status.RestartCount >= threshold {
+				report(FailureRestarts, fmt.Sprintf("restart count crossed %d", threshold), "")
+			}
+		}
+	}
+	return alerts
+}
+
+// containerStatuses 는 informer 캐시의 slice 를 건드리지 않도록 새 slice 에 모은다
+func containerStatuses(pod *coreV1.Pod) []coreV1.ContainerStatus {
+	statuses := make([]coreV1.ContainerStatus, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses))
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	return append(statuses, pod.Status.ContainerStatuses...)
+}
+
+func waitingReason(status coreV1.ContainerStatus) string {
+	if status.State.Waiting == nil {
+		return ""
+	}
+	return status.State.Waiting.Reason
+}
+
+// newTermination 은 이번 업데이트에서 새로 기록된 종료 상태를 반환한다.
+// 재시작되는 컨테이너는 LastTerminationState 에, restartPolicy 가 Never 인 컨테이너는 State 에 남는다
+func newTermination(old, status coreV1.ContainerStatus) *coreV1.ContainerStateTerminated {
+	if terminated := status.State.Terminated; terminated != nil && old.State.Terminated == nil {
+		return terminated
+	}
+	last := status.LastTerminationState.Terminated
+	if last == nil || status.RestartCount == old.RestartCount {
+		return nil
+	}
+	return last
+}
+
+func describeTermination(terminated *coreV1.ContainerStateTerminated) string {
+	if terminated == nil {
+		return ""
+	}
+	value := fmt.Sprintf("%s (exit code %d)", terminated.Reason, terminated.ExitCode)
+	if !terminated.FinishedAt.IsZero() {
+		value += " at " + terminated.FinishedAt.UTC().Format(time.RFC3339)
+	}
+	if message := strings.TrimSpace(terminated.Message); message != "" {
+		value += ": " + message
+	}
+	return value
+}
+
+// shouldSend 는 워크로드와 실패 종류별로 Cooldown 안의 중복 알림을 막는다. 막힌 알림은 다음 알림에 개수로 붙는다
+func (d *podFailureDetector) shouldSend(alert *podFailureAlertType) bool {
+	key := strings.Join([]string{alert.Namespace, alert.OwnerKind, alert.OwnerName, alert.Failure}, "/")
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	state, ok := d.sent[key]
+	if ok && now.Sub(state.sentAt) < d.options.Cooldown {
+		state.suppressed++
+		return false
+	}
+	if ok {
+		alert.Suppressed = state.suppressed
+	}
+	d.sent[key] = &podAlertStateType{sentAt: now}
+
+	// 오래된 항목은 정리해서 맵이 계속 커지지 않게 한다
+	for k, s := range d.sent {
+		if now.Sub(s.sentAt) > 2*d.options.Cooldown {
+			delete(d.sent, k)
+		}
+	}
+	return true
+}
+
+func (d *podFailureDetector) send(alert podFailureAlertType, labels map[string]string) {
+	lines := []string{
+		fmt.Sprintf("*%s:* container `%s` of %s `%s/%s`", alert.Failure, alert.Container, alert.OwnerKind, alert.Namespace, alert.OwnerName),
+		fmt.Sprintf("Pod: `%s` on node `%s`", alert.Pod, alert.Node),
+		fmt.Sprintf("Reason: %s, restarts: `%d`", alert.Reason, alert.RestartCount),
+	}
+	if alert.Message != "" {
+		lines = append(lines, "Message: "+alert.Message)
+	}
+	if alert.LastTermination != "" {
+		lines = append(lines, "Last termination: "+alert.LastTermination)
+	}
+	if alert.Suppressed > 0 {
+		lines = append(lines, fmt.Sprintf("_%d similar alerts for this workload were suppressed since the last notification_", alert.Suppressed))
+	}
+
+	severity := notifier.SeverityWarning
+	if alert.Failure == FailureCrashLoop || alert.Failure == FailureOOMKilled {
+		severity = notifier.SeverityCritical
+	}
+	err := d.notifier.Notify(context.Background(), notifier.Message{
+		Event:     "pod." + strings.ToLower(alert.Failure),
+		Title:     "Pod Failure: " + alert.Failure,
+		Text:      strings.Join(lines, "\n"),
+		Severity:  severity,
+		Namespace: alert.Namespace,
+		Labels:    labels,
+		Data:      alert,
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to send pod failure notification")
+	}
+}
@@ -0,0 +1,58 @@
+package checking_deployment
+
+import (
+	"testing"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTrimmedPodKeepsFailureFields(t *testing.T) {
+	isController := true
+	pod := func(resourceVersion string, state coreV1.ContainerState, restarts int32) *coreV1.Pod {
+		return &coreV1.Pod{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:            "api-7d9f8-abcde",
+				Namespace:       "prd-api",
+				ResourceVersion: resourceVersion,
+				Labels:          map[string]string{"app": "api", "pod-template-hash": "7d9f8"},
+				Annotations:     map[string]string{"large": "annotation"},
+				ManagedFields:   []metaV1.ManagedFieldsEntry{{Manager: "kubelet"}},
+				OwnerReferences: []metaV1.OwnerReference{{Kind: "ReplicaSet", Name: "api-7d9f8", Controller: &isController}},
+			},
+			Spec: coreV1.PodSpec{
+				NodeName:   "node-1",
+				Containers: []coreV1.Container{{Name: "app", Image: "api:1.0"}},
+			},
+			Status: coreV1.PodStatus{
+				ContainerStatuses: []coreV1.ContainerStatus{{Name: "app", State: state, RestartCount: restarts}},
+			},
+		}
+	}
+	trim := func(p *coreV1.Pod) *coreV1.Pod {
+		obj, err := trimPod(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return obj.(*coreV1.Pod)
+	}
+
+	oldPod := trim(pod("1", coreV1.ContainerState{Running: &coreV1.ContainerStateRunning{}}, 4))
+	newPod := trim(pod("2", coreV1.ContainerState{Waiting: &coreV1.ContainerStateWaiting{Reason: FailureCrashLoop}}, 5))
+	if len(newPod.Annotations) != 0 || len(newPod.ManagedFields) != 0 || len(newPod.Spec.Containers) != 0 {
+		t.Errorf("trimmed pod still has unused fields: %+v", newPod)
+	}
+
+	detector := newPodFailureDetector(nil, PodFailureOptions{RestartThresholds: []int32{5}})
+	alerts := detector.detect(oldPod, newPod)
+	if len(alerts) != 2 || alerts[0].Failure != FailureCrashLoop || alerts[1].Failure != FailureRestarts {
+		t.Fatalf("alerts = %+v, want crash loop and restart threshold", alerts)
+	}
+	if alert := alerts[0]; alert.Node != "node-1" || alert.OwnerKind != "Deployment" || alert.OwnerName != "api" {
+		t.Errorf("alert = %+v", alert)
+	}
+
+	if obj, _ := trimPod("not a pod"); obj != "not a pod" {
+		t.Errorf("trimPod changed a non-pod value: %v", obj)
+	}
+}
@@ -0,0 +1,415 @@
+package checking_deployment
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// redactedArg 는 command, args 에서 가린 값 자리에 쓴다
+const redactedArg = "<redacted>"
+
+// detectPodSpecChanges 는 컨테이너(이름 기준, init 컨테이너 포함)와 pod 단위 필드의 변경을 찾는다
+func detectPodSpecChanges(oldSpec, newSpec coreV1.PodSpec) changeSet {
+	var changes changeSet
+	detectContainerListChanges(&changes, "Init container", oldSpec.InitContainers, newSpec.InitContainers)
+	detectContainerListChanges(&changes, "Container", oldSpec.Containers, newSpec.Containers)
+
+	if nodeSelector := detectDataChanges(oldSpec.NodeSelector, newSpec.NodeSelector, inlineValue); len(nodeSelector) > 0 {
+		changes.add(ChangeSpec, append([]string{"- Node selector changes:"}, nodeSelector...)...)
+	}
+	if !reflect.DeepEqual(oldSpec.Tolerations, newSpec.Tolerations) {
+		changes.add(ChangeSpec, fmt.Sprintf("- Tolerations changed from `%s` to `%s`",
+			describeTolerations(oldSpec.Tolerations), describeTolerations(newSpec.Tolerations)))
+	}
+	if !reflect.DeepEqual(oldSpec.Affinity, newSpec.Affinity) {
+		changes.add(ChangeSpec, "- Affinity changed")
+	}
+	if oldSpec.ServiceAccountName != newSpec.ServiceAccountName {
+		changes.add(ChangeSpec, fmt.Sprintf("- Service account changed from `%s` to `%s`", oldSpec.ServiceAccountName, newSpec.ServiceAccountName))
+	}
+	if oldSpec.PriorityClassName != newSpec.PriorityClassName {
+		changes.add(ChangeSpec, fmt.Sprintf("- Priority class changed from `%s` to `%s`", oldSpec.PriorityClassName, newSpec.PriorityClassName))
+	}
+	if volumes := detectVolumeChanges(oldSpec.Volumes, newSpec.Volumes); len(volumes) > 0 {
+		changes.add(ChangeSpec, append([]string{"- Volume changes:"}, volumes...)...)
+	}
+
+	// 위에서 다루지 않는 필드만 바뀐 경우에도 변경 사실은 알린다
+	if changes.IsEmpty() && !reflect.DeepEqual(oldSpec, newSpec) {
+		changes.add(ChangeSpec, "- Other pod spec fields changed")
+	}
+	return changes
+}
+
+func detectContainerListChanges(changes *changeSet, noun string, oldContainers, newContainers []coreV1.Container) {
+	oldByName := make(map[string]coreV1.Container, len(oldContainers))
+	for _, container := range oldContainers {
+		oldByName[container.Name] = container
+	}
+	newNames := make(map[string]bool, len(newContainers))
+	for _, newContainer := range newContainers {
+		newNames[newContainer.Name] = true
+		oldContainer, ok := oldByName[newContainer.Name]
+		if !ok {
+			changes.add(ChangeSpec, fmt.Sprintf("- %s `%s` added (image `%s`)", noun, newContainer.Name, newContainer.Image))
+			changes.images = append(changes.images, imageChangeType{Container: newContainer.Name, New: newContainer.Image})
+			continue
+		}
+		if !reflect.DeepEqual(oldContainer, newContainer) {
+			changes.merge(fmt.Sprintf("- %s `%s` changed:", noun, newContainer.Name), detectContainerChanges(oldContainer, newContainer))
+		}
+	}
+	for _, oldContainer := range oldContainers {
+		if !newNames[oldContainer.Name] {
+			changes.add(ChangeSpec, fmt.Sprintf("- %s `%s` removed (image `%s`)", noun, oldContainer.Name, oldContainer.Image))
+			changes.images = append(changes.images, imageChangeType{Container: oldContainer.Name, Old: oldContainer.Image})
+		}
+	}
+}
+
+// detectContainerChanges 는 같은 이름의 컨테이너 두 개를 필드별로 비교한다
+func detectContainerChanges(oldContainer, newContainer coreV1.Container) changeSet {
+	var changes changeSet
+	if oldContainer.Image != newContainer.Image {
+		changes.add(ChangeImage, fmt.Sprintf("    - Image changed from `%s` to `%s`", oldContainer.Image, newContainer.Image))
+		changes.images = append(changes.images, imageChangeType{Container: newContainer.Name, Old: oldContainer.Image, New: newContainer.Image})
+	}
+	changes.add(ChangeResources, detectResourceChanges(oldContainer.Resources, newContainer.Resources)...)
+
+	if !reflect.DeepEqual(oldContainer.Command, newContainer.Command) {
+		changes.add(ChangeSpec, describeArgsChange("Command", describeCommand(oldContainer.Command), describeCommand(newContainer.Command)))
+	}
+	if !reflect.DeepEqual(oldContainer.Args, newContainer.Args) {
+		changes.add(ChangeSpec, describeArgsChange("Args", describeArgs(oldContainer.Args), describeArgs(newContainer.Args)))
+	}
+	changes.add(ChangeSpec, detectEnvChanges(oldContainer.Env, newContainer.Env)...)
+	if !reflect.DeepEqual(oldContainer.EnvFrom, newContainer.EnvFrom) {
+		changes.add(ChangeSpec, fmt.Sprintf("    - Env sources changed from `%s` to `%s`", describeEnvFrom(oldContainer.EnvFrom), describeEnvFrom(newContainer.EnvFrom)))
+	}
+	if !reflect.DeepEqual(oldContainer.Ports, newContainer.Ports) {
+		changes.add(ChangeSpec, fmt.Sprintf("    - Ports changed from `%s` to `%s`", describePorts(oldContainer.Ports), describePorts(newContainer.Ports)))
+	}
+	changes.add(ChangeSpec, detectVolumeMountChanges(oldContainer.VolumeMounts, newContainer.VolumeMounts)...)
+
+	for _, probe := range []struct {
+		name     string
+		old, new *coreV1.Probe
+	}{
+		{"Liveness", oldContainer.LivenessProbe, newContainer.LivenessProbe},
+		{"Readiness", oldContainer.ReadinessProbe, newContainer.ReadinessProbe},
+		{"Startup", oldContainer.StartupProbe, newContainer.StartupProbe},
+	} {
+		if !reflect.DeepEqual(probe.old, probe.new) {
+			changes.add(ChangeSpec, fmt.Sprintf("    - %s probe changed from `%s` to `%s`", probe.name, describeProbe(probe.old), describeProbe(probe.new)))
+		}
+	}
+
+	if changes.IsEmpty() {
+		changes.add(ChangeSpec, "    - Other container fields changed")
+	}
+	return changes
+}
+
+// detectResourceChanges 는 request/limit 을 리소스 종류별로 변경 전후 값과 함께 보여준다
+func detectResourceChanges(oldResources, newResources coreV1.ResourceRequirements) []string {
+	var changes []string
+	for _, kind := range []struct {
+		name     string
+		old, new coreV1.ResourceList
+	}{
+		{"request", oldResources.Requests, newResources.Requests},
+		{"limit", oldResources.Limits, newResources.Limits},
+	} {
+		for _, name := range resourceNames(kind.old, kind.new) {
+			oldValue, newValue := describeQuantity(kind.old, name), describeQuantity(kind.new, name)
+			if oldValue != newValue {
+				changes = append(changes, fmt.Sprintf("    - %s %s changed from `%s` to `%s`", name, kind.name, oldValue, newValue))
+			}
+		}
+	}
+	return changes
+}
+
+func resourceNames(lists ...coreV1.ResourceList) []coreV1.ResourceName {
+	seen := map[coreV1.ResourceName]bool{}
+	var names []coreV1.ResourceName
+	for _, list := range lists {
+		for name := range list {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+func describeQuantity(list coreV1.ResourceList, name coreV1.ResourceName) string {
+	quantity, ok := list[name]
+	if !ok {
+		return "none"
+	}
+	return quantity.String()
+}
+
+// detectEnvChanges 는 env 변경을 찾는다. 직접 적은 값에도 토큰 같은 비밀 값이 들어가므로 값은 보여주지 않고 바뀐 사실만 알린다
+func detectEnvChanges(oldEnv, newEnv []coreV1.EnvVar) []string {
+	oldByName := make(map[string]coreV1.EnvVar, len(oldEnv))
+	for _, env := range oldEnv {
+		oldByName[env.Name] = env
+	}
+	newNames := make(map[string]bool, len(newEnv))
+
+	var changes []string
+	for _, env := range newEnv {
+		newNames[env.Name] = true
+		oldValue, ok := oldByName[env.Name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("    - Env `%s` added (%s)", env.Name, describeEnvValue(env)))
+		case reflect.DeepEqual(oldValue, env):
+			// 바뀌지 않은 env
+		case oldValue.ValueFrom == nil && env.ValueFrom == nil:
+			changes = append(changes, fmt.Sprintf("    - Env `%s` value changed", env.Name))
+		default:
+			changes = append(changes, fmt.Sprintf("    - Env `%s` changed from %s to %s", env.Name, describeEnvValue(oldValue), describeEnvValue(env)))
+		}
+	}
+	for _, env := range oldEnv {
+		if !newNames[env.Name] {
+			changes = append(changes, fmt.Sprintf("    - Env `%s` removed", env.Name))
+		}
+	}
+	return changes
+}
+
+// describeEnvValue 는 env 값의 출처를 보여준다. 직접 적은 값과 secret 참조의 값은 가린다
+func describeEnvValue(env coreV1.EnvVar) string {
+	from := env.ValueFrom
+	switch {
+	case from == nil:
+		return "literal value"
+	case from.SecretKeyRef != nil:
+		return fmt.Sprintf("secret `%s/%s` (redacted)", from.SecretKeyRef.Name, from.SecretKeyRef.Key)
+	case from.ConfigMapKeyRef != nil:
+		return fmt.Sprintf("configmap `%s/%s`", from.ConfigMapKeyRef.Name, from.ConfigMapKeyRef.Key)
+	case from.FieldRef != nil:
+		return fmt.Sprintf("field `%s`", from.FieldRef.FieldPath)
+	case from.ResourceFieldRef != nil:
+		return fmt.Sprintf("resource `%s`", from.ResourceFieldRef.Resource)
+	}
+	return "unknown source"
+}
+
+func describeEnvFrom(sources []coreV1.EnvFromSource) string {
+	var items []string
+	for _, source := range sources {
+		switch {
+		case source.SecretRef != nil:
+			items = append(items, source.Prefix+"secret/"+source.SecretRef.Name)
+		case source.ConfigMapRef != nil:
+			items = append(items, source.Prefix+"configmap/"+source.ConfigMapRef.Name)
+		}
+	}
+	return joinOrNone(items)
+}
+
+func describePorts(ports []coreV1.ContainerPort) string {
+	var items []string
+	for _, port := range ports {
+		item := fmt.Sprintf("%d/%s", port.ContainerPort, port.Protocol)
+		if port.Name != "" {
+			item = port.Name + ":" + item
+		}
+		items = append(items, item)
+	}
+	return joinOrNone(items)
+}
+
+func detectVolumeMountChanges(oldMounts, newMounts []coreV1.VolumeMount) []string {
+	oldByPath := make(map[string]string, len(oldMounts))
+	for _, mount := range oldMounts {
+		oldByPath[mount.MountPath] = describeVolumeMount(mount)
+	}
+	newByPath := make(map[string]string, len(newMounts))
+	for _, mount := range newMounts {
+		newByPath[mount.MountPath] = describeVolumeMount(mount)
+	}
+
+	var changes []string
+	for _, mount := range newMounts {
+		oldValue, ok := oldByPath[mount.MountPath]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("    - Volume mount `%s` added (`%s`)", mount.MountPath, newByPath[mount.MountPath]))
+		} else if oldValue != newByPath[mount.MountPath] {
+			changes = append(changes, fmt.Sprintf("    - Volume mount `%s` changed from `%s` to `%s`", mount.MountPath, oldValue, newByPath[mount.MountPath]))
+		}
+	}
+	for _, mount := range oldMounts {
+		if _, ok := newByPath[mount.MountPath]; !ok {
+			changes = append(changes, fmt.Sprintf("    - Volume mount `%s` removed (`%s`)", mount.MountPath, oldByPath[mount.MountPath]))
+		}
+	}
+	return changes
+}
+
+func describeVolumeMount(mount coreV1.VolumeMount) string {
+	value := mount.Name
+	if mount.SubPath != "" {
+		value += ":" + mount.SubPath
+	}
+	if mount.ReadOnly {
+		value += " (ro)"
+	}
+	return value
+}
+
+func detectVolumeChanges(oldVolumes, newVolumes []coreV1.Volume) []string {
+	oldByName := make(map[string]coreV1.Volume, len(oldVolumes))
+	for _, volume := range oldVolumes {
+		oldByName[volume.Name] = volume
+	}
+	newNames := make(map[string]bool, len(newVolumes))
+
+	var changes []string
+	for _, volume := range newVolumes {
+		newNames[volume.Name] = true
+		oldVolume, ok := oldByName[volume.Name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("    - Volume `%s` added", volume.Name))
+		} else if !reflect.DeepEqual(oldVolume, volume) {
+			changes = append(changes, fmt.Sprintf("    - Volume `%s` changed", volume.Name))
+		}
+	}
+	for _, volume := range oldVolumes {
+		if !newNames[volume.Name] {
+			changes = append(changes, fmt.Sprintf("    - Volume `%s` removed", volume.Name))
+		}
+	}
+	return changes
+}
+
+func describeProbe(probe *coreV1.Probe) string {
+	if probe == nil {
+		return "none"
+	}
+	handler := "unknown"
+	switch {
+	case probe.HTTPGet != nil:
+		handler = fmt.Sprintf("http-get %s:%s", probe.HTTPGet.Path, probe.HTTPGet.Port.String())
+	case probe.TCPSocket != nil:
+		handler = fmt.Sprintf("tcp-socket :%s", probe.TCPSocket.Port.String())
+	case probe.Exec != nil:
+		handler = "exec " + describeCommand(probe.Exec.Command)
+	case probe.GRPC != nil:
+		handler = fmt.Sprintf("grpc :%d", probe.GRPC.Port)
+	}
+	return fmt.Sprintf("%s delay=%ds timeout=%ds period=%ds success=%d failure=%d", handler,
+		probe.InitialDelaySeconds, probe.TimeoutSeconds, probe.PeriodSeconds, probe.SuccessThreshold, probe.FailureThreshold)
+}
+
+func describeTolerations(tolerations []coreV1.Toleration) string {
+	var items []string
+	for _, t := range tolerations {
+		item := t.Key
+		if t.Operator == coreV1.TolerationOpExists {
+			item += " exists"
+		} else if t.Value != "" {
+			item += "=" + t.Value
+		}
+		if t.Effect != "" {
+			item += ":" + string(t.Effect)
+		}
+		items = append(items, item)
+	}
+	return joinOrNone(items)
+}
+
+func describeDeploymentStrategy(strategy appV1.DeploymentStrategy) string {
+	if strategy.RollingUpdate == nil {
+		return string(strategy.Type)
+	}
+	return fmt.Sprintf("%s maxSurge=%s maxUnavailable=%s", strategy.Type,
+		describeIntOrString(strategy.RollingUpdate.MaxSurge), describeIntOrString(strategy.RollingUpdate.MaxUnavailable))
+}
+
+func describeStatefulSetStrategy(strategy appV1.StatefulSetUpdateStrategy) string {
+	if strategy.RollingUpdate == nil {
+		return string(strategy.Type)
+	}
+	value := string(strategy.Type)
+	if strategy.RollingUpdate.Partition != nil {
+		value += fmt.Sprintf(" partition=%d", *strategy.RollingUpdate.Partition)
+	}
+	if strategy.RollingUpdate.MaxUnavailable != nil {
+		value += " maxUnavailable=" + strategy.RollingUpdate.MaxUnavailable.String()
+	}
+	return value
+}
+
+func describeDaemonSetStrategy(strategy appV1.DaemonSetUpdateStrategy) string {
+	if strategy.RollingUpdate == nil {
+		return string(strategy.Type)
+	}
+	return fmt.Sprintf("%s maxSurge=%s maxUnavailable=%s", strategy.Type,
+		describeIntOrString(strategy.RollingUpdate.MaxSurge), describeIntOrString(strategy.RollingUpdate.MaxUnavailable))
+}
+
+func describeIntOrString(value *intstr.IntOrString) string {
+	if value == nil {
+		return "default"
+	}
+	return value.String()
+}
+
+// describeArgs 는 env 와 마찬가지로 인자 값을 가린다. "--password=..." 같은 flag 는 이름만 보여준다
+func describeArgs(args []string) string {
+	if len(args) == 0 {
+		return "none"
+	}
+	items := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch name, _, hasValue := strings.Cut(arg, "="); {
+		case !strings.HasPrefix(arg, "-"):
+			items = append(items, redactedArg)
+		case hasValue:
+			items = append(items, name+"="+redactedArg)
+		default:
+			items = append(items, arg)
+		}
+	}
+	return strings.Join(items, " ")
+}
+
+// describeArgsChange 는 가린 값만 바뀌어 전후가 같아 보이면 값이 바뀐 사실만 알린다
+func describeArgsChange(field, oldValue, newValue string) string {
+	if oldValue == newValue {
+		return fmt.Sprintf("    - %s values changed", field)
+	}
+	return fmt.Sprintf("    - %s changed from `%s` to `%s`", field, oldValue, newValue)
+}
+
+// describeCommand 는 실행 파일은 그대로 두고 나머지 인자는 describeArgs 처럼 가린다
+func describeCommand(command []string) string {
+	switch len(command) {
+	case 0:
+		return "none"
+	case 1:
+		return command[0]
+	}
+	return command[0] + " " + describeArgs(command[1:])
+}
+
+func joinOrNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,185 @@
+package checking_deployment
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	appV1 "k8s.io/api/apps/v1"
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+const (
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+	KindDaemonSet   = "DaemonSet"
+	KindCronJob     = "CronJob"
+	KindConfigMap   = "ConfigMap"
+	KindSecret      = "Secret"
+)
+
+// ConfigMap 값은 이 길이 이하의 한 줄짜리만 그대로 보여주고, 나머지는 hash 로 보여준다
+const maxInlineValueLength = 64
+
+// describeChanges 는 오브젝트 종류에 맞게 변경 내역을 만든다. autoscaled 면 replicas 변경은 빼고 만든다
+func describeChanges(oldObj, newObj interface{}, autoscaled bool) (kind string, changes changeSet) {
+	switch newObj := newObj.(type) {
+	case *appV1.Deployment:
+		if oldObj, ok := oldObj.(*appV1.Deployment); ok {
+			changes = formatWorkloadChanges(deploymentState(oldObj), deploymentState(newObj), autoscaled)
+		}
+		return KindDeployment, changes
+	case *appV1.StatefulSet:
+		if oldObj, ok := oldObj.(*appV1.StatefulSet); ok {
+			changes = formatWorkloadChanges(statefulSetState(oldObj), statefulSetState(newObj), autoscaled)
+		}
+		return KindStatefulSet, changes
+	case *appV1.DaemonSet:
+		if oldObj, ok := oldObj.(*appV1.DaemonSet); ok {
+			changes = formatWorkloadChanges(daemonSetState(oldObj), daemonSetState(newObj), false)
+		}
+		return KindDaemonSet, changes
+	case *batchV1.CronJob:
+		if oldObj, ok := oldObj.(*batchV1.CronJob); ok {
+			changes = formatCronJobChanges(oldObj, newObj)
+		}
+		return KindCronJob, changes
+	case *coreV1.ConfigMap:
+		if oldObj, ok := oldObj.(*coreV1.ConfigMap); ok {
+			changes = formatConfigMapChanges(oldObj, newObj)
+		}
+		return KindConfigMap, changes
+	case *coreV1.Secret:
+		if oldObj, ok := oldObj.(*coreV1.Secret); ok {
+			changes = formatSecretChanges(oldObj, newObj)
+		}
+		return KindSecret, changes
+	}
+	return "", changes
+}
+
+func deploymentState(d *appV1.Deployment) workloadState {
+	return workloadState{Meta: d.ObjectMeta, Replicas: d.Spec.Replicas, Strategy: describeDeploymentStrategy(d.Spec.Strategy), Template: d.Spec.Template}
+}
+
+func statefulSetState(s *appV1.StatefulSet) workloadState {
+	return workloadState{Meta: s.ObjectMeta, Replicas: s.Spec.Replicas, Strategy: describeStatefulSetStrategy(s.Spec.UpdateStrategy), Template: s.Spec.Template}
+}
+
+func daemonSetState(d *appV1.DaemonSet) workloadState {
+	return workloadState{Meta: d.ObjectMeta, Strategy: describeDaemonSetStrategy(d.Spec.UpdateStrategy), Template: d.Spec.Template}
+}
+
+func formatCronJobChanges(oldCj, newCj *batchV1.CronJob) changeSet {
+	changes := formatWorkloadChanges(
+		workloadState{Meta: oldCj.ObjectMeta, Template: oldCj.Spec.JobTemplate.Spec.Template},
+		workloadState{Meta: newCj.ObjectMeta, Template: newCj.Spec.JobTemplate.Spec.Template},
+		false,
+	)
+	if oldCj.Spec.Schedule != newCj.Spec.Schedule {
+		changes.add(ChangeSpec, fmt.Sprintf("- Schedule changed from `%s` to `%s`", oldCj.Spec.Schedule, newCj.Spec.Schedule))
+	}
+	if suspended(oldCj.Spec.Suspend) != suspended(newCj.Spec.Suspend) {
+		changes.add(ChangeSpec, fmt.Sprintf("- Suspend changed from `%t` to `%t`", suspended(oldCj.Spec.Suspend), suspended(newCj.Spec.Suspend)))
+	}
+	return changes
+}
+
+func formatConfigMapChanges(oldCm, newCm *coreV1.ConfigMap) changeSet {
+	var changes changeSet
+	dataChanges := append(
+		detectDataChanges(oldCm.Data, newCm.Data, inlineValue),
+		detectDataChanges(bytesToStrings(oldCm.BinaryData), bytesToStrings(newCm.BinaryData), hashValue)...,
+	)
+	if len(dataChanges) > 0 {
+		changes.add(ChangeData, append([]string{"- Data changes:"}, dataChanges...)...)
+	}
+	if labelChanges := detectLabelChanges(oldCm.Labels, newCm.Labels); len(labelChanges) > 0 {
+		changes.add(ChangeLabels, append([]string{"- Label changes:"}, labelChanges...)...)
+	}
+	return changes
+}
+
+// formatSecretChanges 는 key 만 보여준다. hash 도 짧은 값은 대입으로 되찾을 수 있으므로 남기지 않는다
+func formatSecretChanges(oldSecret, newSecret *coreV1.Secret) changeSet {
+	var changes changeSet
+	if oldSecret.Type != newSecret.Type {
+		changes.add(ChangeData, fmt.Sprintf("- Type changed from `%s` to `%s`", oldSecret.Type, newSecret.Type))
+	}
+	if dataChanges := detectDataChanges(bytesToStrings(oldSecret.Data), bytesToStrings(newSecret.Data), nil); len(dataChanges) > 0 {
+		changes.add(ChangeData, append([]string{"- Data changes:"}, dataChanges...)...)
+	}
+	if labelChanges := detectLabelChanges(oldSecret.Labels, newSecret.Labels); len(labelChanges) > 0 {
+		changes.add(ChangeLabels, append([]string{"- Label changes:"}, labelChanges...)...)
+	}
+	return changes
+}
+
+// detectDataChanges 는 key 단위로 추가/변경/삭제를 찾는다. 값은 display 로 변환해서 보여주고, display 가 nil 이면 key 만 보여준다
+func detectDataChanges(oldData, newData map[string]string, display func(string) string) []string {
+	keys := make([]string, 0, len(oldData)+len(newData))
+	for key := range oldData {
+		keys = append(keys, key)
+	}
+	for key := range newData {
+		if _, ok := oldData[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []string
+	for _, key := range keys {
+		oldVal, inOld := oldData[key]
+		newVal, inNew := newData[key]
+		switch {
+		case display == nil && (!inOld || !inNew || oldVal != newVal):
+			changes = append(changes, fmt.Sprintf("    - Key `%s` %s", key, keyChange(inOld, inNew)))
+		case !inOld:
+			changes = append(changes, fmt.Sprintf("    - Key `%s` added (%s)", key, display(newVal)))
+		case !inNew:
+			changes = append(changes, fmt.Sprintf("    - Key `%s` removed (%s)", key, display(oldVal)))
+		case oldVal != newVal:
+			changes = append(changes, fmt.Sprintf("    - Key `%s` changed from %s to %s", key, display(oldVal), display(newVal)))
+		}
+	}
+	return changes
+}
+
+func keyChange(inOld, inNew bool) string {
+	switch {
+	case !inOld:
+		return "added"
+	case !inNew:
+		return "removed"
+	}
+	return "changed"
+}
+
+func inlineValue(value string) string {
+	if len(value) <= maxInlineValueLength && !strings.Contains(value, "\n") {
+		return fmt.Sprintf("`%s`", value)
+	}
+	return hashValue(value)
+}
+
+// hashValue 는 값을 노출하지 않고 변경 여부만 비교할 수 있도록 sha256 앞부분을 반환
+func hashValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:])[:12]
+}
+
+func bytesToStrings(data map[string][]byte) map[string]string {
+	result := make(map[string]string, len(data))
+	for key, value := range data {
+		result[key] = string(value)
+	}
+	return result
+}
+
+func suspended(suspend *bool) bool {
+	return suspend != nil && *suspend
+}
@@ -0,0 +1,361 @@
+package checking_deployment
+
+import (
+	"client-go/internal/pkg/kube"
+	"client-go/internal/pkg/notifier"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	defaultRolloutPollInterval = 10 * time.Second
+	defaultRolloutTimeout      = 30 * time.Minute
+	defaultRolloutLogTailLines = 20
+
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+)
+
+// 새 ReplicaSet 의 pod 가 이 상태로 멈춰 있으면 rollout 실패로 본다
+var stuckWaitingReasons = map[string]bool{
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+}
+
+type RolloutOptions struct {
+	PollInterval time.Duration
+	Timeout      time.Duration
+	LogTailLines int64
+}
+
+// RolloutOptionsFromEnv 는 ROLLOUT_POLL_INTERVAL(기본 10s), ROLLOUT_TIMEOUT(기본 30m),
+// ROLLOUT_LOG_TAIL_LINES(기본 20) 로 rollout 추적 옵션을 만든다
+func RolloutOptionsFromEnv() RolloutOptions {
+	opts := RolloutOptions{
+		PollInterval: defaultRolloutPollInterval,
+		Timeout:      defaultRolloutTimeout,
+		LogTailLines: defaultRolloutLogTailLines,
+	}
+	if interval, err := time.ParseDuration(os.Getenv("ROLLOUT_POLL_INTERVAL")); err == nil && interval > 0 {
+		opts.PollInterval = interval
+	}
+	if timeout, err := time.ParseDuration(os.Getenv("ROLLOUT_TIMEOUT")); err == nil && timeout > 0 {
+		opts.Timeout = timeout
+	}
+	if lines, err := strconv.ParseInt(os.Getenv("ROLLOUT_LOG_TAIL_LINES"), 10, 64); err == nil && lines >= 0 {
+		opts.LogTailLines = lines
+	}
+	return opts
+}
+
+// rolloutTracker 는 pod template 이 바뀐 Deployment 의 rollout 을 끝날 때까지 따라가서 결과를 알린다.
+// 같은 Deployment 에 새 rollout 이 시작되면 이전 추적은 취소한다
+type rolloutTracker struct {
+	ctx       context.Context
+	clientSet kubernetes.Interface
+	notifier  notifier.Notifier
+	options   RolloutOptions
+
+	mu     sync.Mutex
+	active map[string]*trackedRollout
+}
+
+type trackedRollout struct {
+	cancel context.CancelFunc
+}
+
+type podFailureType struct {
+	Pod       string
+	Container string
+	Reason    string
+	Message   string
+	Logs      string
+}
+
+func newRolloutTracker(ctx context.Context, clientSet kubernetes.Interface, n notifier.Notifier, options RolloutOptions) *rolloutTracker {
+	return &rolloutTracker{ctx: ctx, clientSet: clientSet, notifier: n, options: options, active: map[string]*trackedRollout{}}
+}
+
+// observe 는 Deployment 업데이트 중 pod template 이 바뀐 경우에만 추적을 시작한다
+func (t *rolloutTracker) observe(oldObj, newObj interface{}) {
+	oldDep, ok := oldObj.(*appV1.Deployment)
+	if !ok {
+		return
+	}
+	newDep, ok := newObj.(*appV1.Deployment)
+	if !ok || reflect.DeepEqual(oldDep.Spec.Template, newDep.Spec.Template) {
+		return
+	}
+
+	key := newDep.Namespace + "/" + newDep.Name
+	ctx, cancel := context.WithTimeout(t.ctx, t.options.Timeout)
+	rollout := &trackedRollout{cancel: cancel}
+
+	t.mu.Lock()
+	if previous, ok := t.active[key]; ok {
+		previous.cancel()
+	}
+	t.active[key] = rollout
+	t.mu.Unlock()
+
+	go func() {
+		defer t.finish(key, rollout)
+		t.follow(ctx, newDep, time.Now())
+	}()
+}
+
+// finish 는 추적이 끝난 rollout 을 목록에서 지운다. 이미 새 rollout 으로 교체된 경우는 건드리지 않는다
+func (t *rolloutTracker) finish(key string, rollout *trackedRollout) {
+	rollout.cancel()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.active[key] == rollout {
+		delete(t.active, key)
+	}
+}
+
+func (t *rolloutTracker) Active() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.active)
+}
+
+// follow 는 start 의 pod template 이 반영될 때까지 따라간다. start 는 template 이 바뀐 업데이트라
+// revision annotation 은 아직 이전 값이다(deployment controller 가 새 ReplicaSet 을 만든 뒤 올린다)
+func (t *rolloutTracker) follow(ctx context.Context, start *appV1.Deployment, startedAt time.Time) {
+	namespace, name, generation := start.Namespace, start.Name, start.Generation
+	previousRevision := start.Annotations[revisionAnnotation]
+	ticker := time.NewTicker(t.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				t.notifyFailure(namespace, name, nil, previousRevision, fmt.Sprintf("rollout did not finish within %s", t.options.Timeout), nil)
+			}
+			return
+		case <-ticker.C:
+		}
+
+		dep, err := t.clientSet.AppsV1().Deployments(namespace).Get(ctx, name, metaV1.GetOptions{})
+		if apiErrors.IsNotFound(err) {
+			return
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.WithError(err).Warnf("Failed to get deployment %s/%s while following rollout", namespace, name)
+			}
+			continue
+		}
+		if dep.Spec.Paused {
+			return
+		}
+		// 추적 중에 pod template 이 다시 바뀌었다면 새 rollout 쪽에서 결과를 알린다.
+		// HPA 나 사용자가 replicas 만 바꾼 경우에도 generation 이 오르므로 기준만 옮기고 계속 따라간다
+		if dep.Generation > generation {
+			if !reflect.DeepEqual(dep.Spec.Template, start.Spec.Template) {
+				return
+			}
+			generation = dep.Generation
+		}
+		if dep.Status.ObservedGeneration < dep.Generation {
+			continue
+		}
+
+		failures := t.newReplicaSetFailures(ctx, dep)
+		if cond := deploymentCondition(dep, appV1.DeploymentProgressing); cond != nil && cond.Reason == "ProgressDeadlineExceeded" {
+			t.notifyFailure(namespace, name, dep, previousRevision, "ProgressDeadlineExceeded: "+cond.Message, failures)
+			return
+		}
+		if len(failures) > 0 {
+			t.notifyFailure(namespace, name, dep, previousRevision, failures[0].Reason, failures)
+			return
+		}
+		if rolloutComplete(dep) {
+			t.notifySuccess(dep, previousRevision, time.Since(startedAt))
+			return
+		}
+	}
+}
+
+func describeRevision(oldRevision, newRevision string) string {
+	if oldRevision != "" && newRevision != "" && oldRevision != newRevision {
+		return oldRevision + " -> " + newRevision
+	}
+	return newRevision
+}
+
+// rolloutComplete 는 kubectl rollout status 와 같은 기준으로 완료 여부를 판단한다
+func rolloutComplete(dep *appV1.Deployment) bool {
+	desired := int32(1)
+	if dep.Spec.Replicas != nil {
+		desired = *dep.Spec.Replicas
+	}
+	status := dep.Status
+	return status.UpdatedReplicas == desired && status.Replicas == status.UpdatedReplicas && status.AvailableReplicas == status.UpdatedReplicas
+}
+
+func deploymentCondition(dep *appV1.Deployment, condType appV1.DeploymentConditionType) *appV1.DeploymentCondition {
+	for i := range dep.Status.Conditions {
+		if dep.Status.Conditions[i].Type == condType {
+			return &dep.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// newReplicaSetFailures 는 현재 revision 의 ReplicaSet 에 속한 pod 중 멈춰 있는 컨테이너를 찾는다
+func (t *rolloutTracker) newReplicaSetFailures(ctx context.Context, dep *appV1.Deployment) []podFailureType {
+	selector, err := metaV1.LabelSelectorAsSelector(dep.Spec.Selector)
+	if err != nil {
+		return nil
+	}
+
+	var newRS *appV1.ReplicaSet
+	err = kube.EachReplicaSet(ctx, t.clientSet, dep.Namespace, metaV1.ListOptions{LabelSelector: selector.String()}, func(rs *appV1.ReplicaSet) error {
+		if metaV1.IsControlledBy(rs, dep) && rs.Annotations[revisionAnnotation] == dep.Annotations[revisionAnnotation] {
+			newRS = rs
+		}
+		return nil
+	})
+	if err != nil || newRS == nil {
+		return nil
+	}
+
+	podSelector := labels.Set{appV1.DefaultDeploymentUniqueLabelKey: newRS.Labels[appV1.DefaultDeploymentUniqueLabelKey]}.AsSelector().String()
+	if s := selector.String(); s != "" {
+		podSelector = s + "," + podSelector
+	}
+
+	var failures []podFailureType
+	err = kube.EachPod(ctx, t.clientSet, dep.Namespace, metaV1.ListOptions{LabelSelector: podSelector}, func(pod *coreV1.Pod) error {
+		if !metaV1.IsControlledBy(pod, newRS) {
+			return nil
+		}
+		statuses := append(append([]coreV1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if status.State.Waiting == nil || !stuckWaitingReasons[status.State.Waiting.Reason] {
+				continue
+			}
+			failures = append(failures, podFailureType{
+				Pod:       pod.Name,
+				Container: status.Name,
+				Reason:    status.State.Waiting.Reason,
+				Message:   status.State.Waiting.Message,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Warnf("Failed to list pods of replicaset %s/%s", newRS.Namespace, newRS.Name)
+	}
+
+	// 로그는 알림이 너무 길어지지 않도록 첫 번째 실패 컨테이너만 가져온다
+	if len(failures) > 0 && t.options.LogTailLines > 0 {
+		failures[0].Logs = t.containerLogs(ctx, dep.Namespace, failures[0])
+	}
+	return failures
+}
+
+// containerLogs 는 컨테이너의 마지막 로그를 가져온다. CrashLoopBackOff 는 직전에 죽은 컨테이너의 로그를 본다
+func (t *rolloutTracker) containerLogs(ctx context.Context, namespace string, failure podFailureType) string {
+	tailLines := t.options.LogTailLines
+	opts := &coreV1.PodLogOptions{
+		Container: failure.Container,
+		TailLines: &tailLines,
+		Previous:  failure.Reason == "CrashLoopBackOff",
+	}
+	data, err := t.clientSet.CoreV1().Pods(namespace).GetLogs(failure.Pod, opts).DoRaw(ctx)
+	if err != nil {
+		log.WithError(err).Debugf("Failed to get logs of %s/%s", failure.Pod, failure.Container)
+		return ""
+	}
+	return strings.TrimRight(string(data), "\n")
+}
+
+// rolloutNotificationType 은 rollout 알림 템플릿에 Message.Data 로 넘기는 값.
+// Revision 은 ObservedGeneration 이 따라잡은 뒤의 값이라 새 ReplicaSet 의 revision 이다 ("3 -> 4" 형태)
+type rolloutNotificationType struct {
+	Namespace string
+	Name      string
+	Revision  string
+	Duration  string
+	Cause     string
+	Failures  []podFailureType
+}
+
+func (t *rolloutTracker) notifySuccess(dep *appV1.Deployment, previousRevision string, took time.Duration) {
+	revision := describeRevision(previousRevision, dep.Annotations[revisionAnnotation])
+	err := t.notifier.Notify(context.Background(), notifier.Message{
+		Event:     "deployment.rollout.succeeded",
+		Title:     "Deployment Rollout Succeeded",
+		Text:      fmt.Sprintf("*Rollout completed:* `%s/%s` (revision %s) in %s", dep.Namespace, dep.Name, revision, took.Round(time.Second)),
+		Severity:  notifier.SeverityInfo,
+		Namespace: dep.Namespace,
+		Labels:    dep.Labels,
+		Data: rolloutNotificationType{
+			Namespace: dep.Namespace,
+			Name:      dep.Name,
+			Revision:  revision,
+			Duration:  took.Round(time.Second).String(),
+		},
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to send rollout notification")
+	}
+}
+
+func (t *rolloutTracker) notifyFailure(namespace, name string, dep *appV1.Deployment, previousRevision, cause string, failures []podFailureType) {
+	// 시간 초과로 끝난 경우(dep 가 nil)는 새 revision 을 모른다
+	var revision string
+	if dep != nil {
+		revision = describeRevision(previousRevision, dep.Annotations[revisionAnnotation])
+	}
+	lines := []string{fmt.Sprintf("*Rollout failed:* `%s/%s`", namespace, name), "Cause: " + cause}
+	if revision != "" {
+		lines = append(lines, "Revision: "+revision)
+	}
+	for _, failure := range failures {
+		line := fmt.Sprintf("- Pod `%s` container `%s`: %s", failure.Pod, failure.Container, failure.Reason)
+		if failure.Message != "" {
+			line += " (" + failure.Message + ")"
+		}
+		lines = append(lines, line)
+		if failure.Logs != "" {
+			lines = append(lines, fmt.Sprintf("Last log lines of `%s`:\n```\n%s\n```", failure.Container, failure.Logs))
+		}
+	}
+
+	msg := notifier.Message{
+		Event:     "deployment.rollout.failed",
+		Title:     "Deployment Rollout Failed",
+		Text:      strings.Join(lines, "\n"),
+		Severity:  notifier.SeverityCritical,
+		Namespace: namespace,
+	}
+	data := rolloutNotificationType{Namespace: namespace, Name: name, Revision: revision, Cause: cause, Failures: failures}
+	if dep != nil {
+		msg.Labels = dep.Labels
+	}
+	msg.Data = data
+	if err := t.notifier.Notify(context.Background(), msg); err != nil {
+		log.WithError(err).Error("Failed to send rollout notification")
+	}
+}
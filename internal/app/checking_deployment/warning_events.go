@@ -0,0 +1,369 @@
+package checking_deployment
+
+import (
+	"client-go/internal/app/pod_metadata"
+	"client-go/internal/pkg/notifier"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	coreV1 "k8s.io/api/core/v1"
+	eventsV1 "k8s.io/api/events/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	EventAPICore   = "core"
+	EventAPIEvents = "events.k8s.io"
+
+	defaultEventWindow = 5 * time.Minute
+	allWarningReasons  = "*"
+
+	labelLookupTimeout = 5 * time.Second
+)
+
+// BackOff 는 pod 실패 감지(CrashLoopBackOff, ImagePullBackOff)와 겹쳐서 기본에서 뺀다
+var defaultWarningReasons = []string{"FailedScheduling", "FailedMount", "NodeNotReady", "FailedCreatePodSandBox"}
+
+// normalTypeReasons 는 Warning 이 아닌 type 으로 기록되지만 알려야 하는 reason
+var normalTypeReasons = map[string]bool{"NodeNotReady": true}
+
+// WarningEventOptions 는 Warning 이벤트 알림 설정. 클러스터 전체의 Event 를 캐시하므로 기본으로 꺼져 있다
+type WarningEventOptions struct {
+	Enabled bool
+	// API 는 EventAPICore(core/v1) 또는 EventAPIEvents(events.k8s.io/v1).
+	// 두 API 는 같은 저장소의 Event 를 다른 형태로 보여줄 뿐이라 둘 다 보면 같은 이벤트를 두 번 알리게 되므로 하나만 본다
+	API string
+	// Reasons 에 있는 reason 의 Warning 이벤트를 보낸다. NodeNotReady 처럼 Normal 로 기록되는 reason 은 type 과 상관없이 보낸다.
+	// "*" 가 있으면 모든 Warning 이벤트를 보낸다
+	Reasons []string
+	// Window 동안 같은 대상(involved object)의 이벤트는 첫 건만 바로 보내고 나머지는 모아서 한 번에 보낸다
+	Window time.Duration
+}
+
+// WarningEventOptionsFromEnv 는 WARNING_EVENT_ALERTS(기본 false), WARNING_EVENT_API(기본 events.k8s.io),
+// WARNING_EVENT_REASONS(쉼표 구분), WARNING_EVENT_WINDOW(기본 5m) 로 옵션을 만든다
+func WarningEventOptionsFromEnv() (WarningEventOptions, error) {
+	opts := WarningEventOptions{
+		Enabled: os.Getenv("WARNING_EVENT_ALERTS") == "true",
+		API:     EventAPIEvents,
+		Reasons: defaultWarningReasons,
+		Window:  defaultEventWindow,
+	}
+	if api := os.Getenv("WARNING_EVENT_API"); api != "" {
+		opts.API = api
+	}
+	if reasons := pod_metadata.SplitList(os.Getenv("WARNING_EVENT_REASONS")); len(reasons) > 0 {
+		opts.Reasons = reasons
+	}
+	if window, err := time.ParseDuration(os.Getenv("WARNING_EVENT_WINDOW")); err == nil && window > 0 {
+		opts.Window = window
+	}
+	if opts.API != EventAPICore && opts.API != EventAPIEvents {
+		return opts, fmt.Errorf("invalid WARNING_EVENT_API %q: use %s or %s", opts.API, EventAPICore, EventAPIEvents)
+	}
+	return opts, nil
+}
+
+func (o WarningEventOptions) matches(event warningEventType) bool {
+	if event.Type != coreV1.EventTypeWarning && !normalTypeReasons[event.Reason] {
+		return false
+	}
+	for _, reason := range o.Reasons {
+		if reason == event.Reason || (reason == allWarningReasons && event.Type == coreV1.EventTypeWarning) {
+			return true
+		}
+	}
+	return false
+}
+
+// warningEventType 은 core/v1 과 events.k8s.io/v1 이벤트에서 쓰는 값만 모은 것
+type warningEventType struct {
+	Type      string
+	Reason    string
+	Message   string
+	Source    string
+	Kind      string
+	Namespace string
+	Name      string
+	Count     int32
+}
+
+func (e warningEventType) objectKey() string {
+	return e.Namespace + "/" + e.Kind + "/" + e.Name
+}
+
+func fromCoreEvent(obj interface{}) (warningEventType, bool) {
+	event, ok := obj.(*coreV1.Event)
+	if !ok {
+		return warningEventType{}, false
+	}
+	source := event.ReportingController
+	if source == "" {
+		source = event.Source.Component
+	}
+	count := event.Count
+	if event.Series != nil {
+		count = event.Series.Count
+	}
+	// 처음 기록된 이벤트는 count 가 비어 있을 수 있다
+	count = max(count, 1)
+	return warningEventType{
+		Type:      event.Type,
+		Reason:    event.Reason,
+		Message:   event.Message,
+		Source:    source,
+		Kind:      event.InvolvedObject.Kind,
+		Namespace: event.InvolvedObject.Namespace,
+		Name:      event.InvolvedObject.Name,
+		Count:     count,
+	}, true
+}
+
+func fromEventsV1Event(obj interface{}) (warningEventType, bool) {
+	event, ok := obj.(*eventsV1.Event)
+	if !ok {
+		return warningEventType{}, false
+	}
+	source := event.ReportingController
+	if source == "" {
+		source = event.DeprecatedSource.Component
+	}
+	count := event.DeprecatedCount
+	if event.Series != nil {
+		count = event.Series.Count
+	}
+	// 처음 기록된 이벤트는 count 가 비어 있을 수 있다
+	count = max(count, 1)
+	return warningEventType{
+		Type:      event.Type,
+		Reason:    event.Reason,
+		Message:   event.Note,
+		Source:    source,
+		Kind:      event.Regarding.Kind,
+		Namespace: event.Regarding.Namespace,
+		Name:      event.Regarding.Name,
+		Count:     count,
+	}, true
+}
+
+// warningEventAggregator 는 대상별로 이벤트를 모아 알림 수를 줄인다
+type warningEventAggregator struct {
+	ctx       context.Context
+	clientSet kubernetes.Interface
+	notifier  notifier.Notifier
+	options   WarningEventOptions
+
+	mu      sync.Mutex
+	windows map[string]*eventWindowType
+}
+
+type eventWindowType struct {
+	first warningEventType
+	// labels 는 첫 이벤트를 보낼 때 읽은 대상의 label. 모아서 보내는 알림에도 같은 값을 쓴다
+	labels  map[string]string
+	pending map[string]*eventSummaryType
+}
+
+// eventSummaryType 은 Window 동안 모인 같은 reason 의 이벤트
+type eventSummaryType struct {
+	Reason  string
+	Message string
+	Count   int32
+}
+
+// eventDigestType 은 모아서 보내는 알림의 템플릿에 Message.Data 로 넘기는 값
+type eventDigestType struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Window    string
+	Events    []eventSummaryType
+}
+
+func newWarningEventAggregator(ctx context.Context, clientSet kubernetes.Interface, n notifier.Notifier, options WarningEventOptions) *warningEventAggregator {
+	return &warningEventAggregator{ctx: ctx, clientSet: clientSet, notifier: n, options: options, windows: map[string]*eventWindowType{}}
+}
+
+// informer 는 설정한 API 의 Event informer 에 핸들러를 붙여 반환한다
+func (a *warningEventAggregator) informer(factory informers.SharedInformerFactory) (cache.SharedIndexInformer, error) {
+	informer := factory.Events().V1().Events().Informer()
+	convert := fromEventsV1Event
+	if a.options.API == EventAPICore {
+		informer = factory.Core().V1().Events().Informer()
+		convert = fromCoreEvent
+	}
+
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			// 시작 시점에 이미 있던 이벤트는 보내지 않는다
+			if isInInitialList {
+				return
+			}
+			if event, ok := convert(obj); ok {
+				a.observe(event, event.Count)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldEvent, ok := convert(oldObj)
+			if !ok {
+				return
+			}
+			// 같은 이벤트가 반복되면 새 객체 대신 count 가 늘어난다
+			if event, ok := convert(newObj); ok && event.Count > oldEvent.Count {
+				a.observe(event, event.Count-oldEvent.Count)
+			}
+		},
+	})
+	return informer, err
+}
+
+func (a *warningEventAggregator) observe(event warningEventType, occurrences int32) {
+	if !a.options.matches(event) {
+		return
+	}
+	key := event.objectKey()
+
+	a.mu.Lock()
+	window, ok := a.windows[key]
+	if ok {
+		summary := window.pending[event.Reason]
+		if summary == nil {
+			summary = &eventSummaryType{Reason: event.Reason}
+			window.pending[event.Reason] = summary
+		}
+		summary.Count += occurrences
+		summary.Message = event.Message
+		a.mu.Unlock()
+		return
+	}
+	window = &eventWindowType{first: event, pending: map[string]*eventSummaryType{}}
+	a.windows[key] = window
+	a.mu.Unlock()
+
+	labels := a.objectLabels(event)
+	a.mu.Lock()
+	window.labels = labels
+	a.mu.Unlock()
+	a.sendEvent(event, labels)
+	time.AfterFunc(a.options.Window, func() { a.flush(key) })
+}
+
+// flush 는 Window 동안 모인 이벤트를 한 번에 보낸다. 모인 것이 있으면 다음 Window 를 다시 시작한다
+func (a *warningEventAggregator) flush(key string) {
+	a.mu.Lock()
+	window := a.windows[key]
+	if window == nil || len(window.pending) == 0 || a.ctx.Err() != nil {
+		delete(a.windows, key)
+		a.mu.Unlock()
+		return
+	}
+	summaries := make([]eventSummaryType, 0, len(window.pending))
+	for _, summary := range window.pending {
+		summaries = append(summaries, *summary)
+	}
+	window.pending = map[string]*eventSummaryType{}
+	labels := window.labels
+	a.mu.Unlock()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Count > summaries[j].Count
+	})
+	a.sendSummary(window.first, labels, summaries)
+	time.AfterFunc(a.options.Window, func() { a.flush(key) })
+}
+
+// objectLabels 는 route 의 LabelSelector 로 거를 수 있도록 이벤트 대상의 label 을 읽는다.
+// Pod, Node 와 watcher 가 보는 워크로드 종류만 지원하고, 그 밖의 대상이나 이미 지워진 대상은 label 없이 보낸다
+func (a *warningEventAggregator) objectLabels(event warningEventType) map[string]string {
+	ctx, cancel := context.WithTimeout(a.ctx, labelLookupTimeout)
+	defer cancel()
+
+	var object metaV1.Object
+	var err error
+	namespace, name, opts := event.Namespace, event.Name, metaV1.GetOptions{}
+	switch event.Kind {
+	case "Pod":
+		object, err = a.clientSet.CoreV1().Pods(namespace).Get(ctx, name, opts)
+	case "Node":
+		object, err = a.clientSet.CoreV1().Nodes().Get(ctx, name, opts)
+	case KindDeployment:
+		object, err = a.clientSet.AppsV1().Deployments(namespace).Get(ctx, name, opts)
+	case KindStatefulSet:
+		object, err = a.clientSet.AppsV1().StatefulSets(namespace).Get(ctx, name, opts)
+	case KindDaemonSet:
+		object, err = a.clientSet.AppsV1().DaemonSets(namespace).Get(ctx, name, opts)
+	case "ReplicaSet":
+		object, err = a.clientSet.AppsV1().ReplicaSets(namespace).Get(ctx, name, opts)
+	case KindCronJob:
+		object, err = a.clientSet.BatchV1().CronJobs(namespace).Get(ctx, name, opts)
+	case "Job":
+		object, err = a.clientSet.BatchV1().Jobs(namespace).Get(ctx, name, opts)
+	default:
+		return nil
+	}
+	if err != nil {
+		if !apiErrors.IsNotFound(err) {
+			log.WithError(err).Debugf("Failed to get labels of %s %s", event.Kind, objectName(event))
+		}
+		return nil
+	}
+	return object.GetLabels()
+}
+
+func (a *warningEventAggregator) sendEvent(event warningEventType, labels map[string]string) {
+	text := fmt.Sprintf("*%s:* %s `%s`\n%s\nSource: `%s`", event.Reason, event.Kind, objectName(event), event.Message, event.Source)
+	a.notify(notifier.Message{
+		Event:     "event." + strings.ToLower(event.Reason),
+		Title:     "Kubernetes Warning Event: " + event.Reason,
+		Text:      text,
+		Severity:  notifier.SeverityWarning,
+		Namespace: event.Namespace,
+		Labels:    labels,
+		Data:      event,
+	})
+}
+
+func (a *warningEventAggregator) sendSummary(object warningEventType, labels map[string]string, summaries []eventSummaryType) {
+	lines := []string{fmt.Sprintf("*More events for %s `%s` in the last %s:*", object.Kind, objectName(object), a.options.Window)}
+	for _, summary := range summaries {
+		lines = append(lines, fmt.Sprintf("- `%s` x%d: %s", summary.Reason, summary.Count, summary.Message))
+	}
+	a.notify(notifier.Message{
+		Event:     "event.aggregated",
+		Title:     "Kubernetes Warning Events",
+		Text:      strings.Join(lines, "\n"),
+		Severity:  notifier.SeverityWarning,
+		Namespace: object.Namespace,
+		Labels:    labels,
+		Data: eventDigestType{
+			Kind:      object.Kind,
+			Namespace: object.Namespace,
+			Name:      object.Name,
+			Window:    a.options.Window.String(),
+			Events:    summaries,
+		},
+	})
+}
+
+func (a *warningEventAggregator) notify(msg notifier.Message) {
+	if err := a.notifier.Notify(context.Background(), msg); err != nil {
+		log.WithError(err).Error("Failed to send warning event notification")
+	}
+}
+
+func objectName(event warningEventType) string {
+	if event.Namespace == "" {
+		return event.Name
+	}
+	return event.Namespace + "/" + event.Name
+}
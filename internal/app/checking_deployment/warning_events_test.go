@@ -0,0 +1,33 @@
+package checking_deployment
+
+import (
+	"testing"
+
+	coreV1 "k8s.io/api/core/v1"
+)
+
+func TestWarningEventOptionsMatches(t *testing.T) {
+	options := WarningEventOptions{Reasons: defaultWarningReasons}
+	tests := []struct {
+		name  string
+		event warningEventType
+		want  bool
+	}{
+		{"configured warning", warningEventType{Type: coreV1.EventTypeWarning, Reason: "FailedScheduling"}, true},
+		{"normal with configured reason", warningEventType{Type: coreV1.EventTypeNormal, Reason: "FailedMount"}, false},
+		{"normal NodeNotReady", warningEventType{Type: coreV1.EventTypeNormal, Reason: "NodeNotReady"}, true},
+		{"BackOff is left to pod failure alerts", warningEventType{Type: coreV1.EventTypeWarning, Reason: "BackOff"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := options.matches(tt.event); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	all := WarningEventOptions{Reasons: []string{allWarningReasons}}
+	if !all.matches(warningEventType{Type: coreV1.EventTypeWarning, Reason: "Unhealthy"}) || all.matches(warningEventType{Type: coreV1.EventTypeNormal, Reason: "Pulled"}) {
+		t.Error(`"*" should match every Warning event and nothing else`)
+	}
+}
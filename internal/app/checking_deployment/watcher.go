@@ -0,0 +1,233 @@
+package checking_deployment
+
+import (
+	"client-go/internal/pkg/notifier"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const resyncPeriod = 30 * time.Second
+
+// Watcher 는 워크로드(Deployment, StatefulSet, DaemonSet, CronJob)와 설정(ConfigMap, Secret, 선택)
+// 변경 감지, pod 실패 감지, Warning 이벤트 informer 를 한 벌만 실행하도록 관리한다. Start 를 여러 번 호출해도 informer 가 중복으로 뜨지 않는다.
+type Watcher struct {
+	clientSet kubernetes.Interface
+	notifier  notifier.Notifier
+	options   WatcherOptions
+
+	mu        sync.Mutex
+	stopCh    chan struct{}
+	factories []informers.SharedInformerFactory
+	cancel    context.CancelFunc
+	rollouts  *rolloutTracker
+	hasSynced []cache.InformerSynced
+	startedAt time.Time
+
+	lastEventAt     atomic.Int64
+	eventsProcessed atomic.Int64
+}
+
+type watcherStatusType struct {
+	Running         bool
+	Synced          bool
+	StartedAt       *time.Time
+	LastEventAt     *time.Time
+	EventsProcessed int64
+	ActiveRollouts  int
+}
+
+// WatcherOptions 는 변경 이력 저장소와 설정 변경 감지 범위, rollout 추적, 이미지 정책, pod 실패와 Warning 이벤트 알림 설정
+type WatcherOptions struct {
+	History       *History
+	ConfigWatch   ConfigWatchOptions
+	Rollout       RolloutOptions
+	ImagePolicy   ImagePolicy
+	PodFailure    PodFailureOptions
+	WarningEvents WarningEventOptions
+}
+
+func WatcherOptionsFromEnv() (WatcherOptions, error) {
+	policy, err := ImagePolicyFromEnv()
+	if err != nil {
+		return WatcherOptions{}, err
+	}
+	warningEvents, err := WarningEventOptionsFromEnv()
+	if err != nil {
+		return WatcherOptions{}, err
+	}
+	configWatch, err := ConfigWatchOptionsFromEnv()
+	if err != nil {
+		return WatcherOptions{}, err
+	}
+	return WatcherOptions{
+		History:       NewHistoryFromEnv(),
+		ConfigWatch:   configWatch,
+		Rollout:       RolloutOptionsFromEnv(),
+		ImagePolicy:   policy,
+		PodFailure:    PodFailureOptionsFromEnv(),
+		WarningEvents: warningEvents,
+	}, nil
+}
+
+func NewWatcher(clientSet kubernetes.Interface, n notifier.Notifier, options WatcherOptions) *Watcher {
+	return &Watcher{clientSet: clientSet, notifier: n, options: options}
+}
+
+// Start 는 watcher 가 멈춰 있을 때만 informer 를 실행하고, 새로 시작했는지 여부를 반환
+func (w *Watcher) Start() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stopCh != nil {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rollouts := newRolloutTracker(ctx, w.clientSet, w.notifier, w.options.Rollout)
+
+	factory := informers.NewSharedInformerFactory(w.clientSet, resyncPeriod)
+	factories := []informers.SharedInformerFactory{factory}
+	watched := map[string]cache.SharedIndexInformer{
+		KindDeployment:  factory.Apps().V1().Deployments().Informer(),
+		KindStatefulSet: factory.Apps().V1().StatefulSets().Informer(),
+		KindDaemonSet:   factory.Apps().V1().DaemonSets().Informer(),
+		KindCronJob:     factory.Batch().V1().CronJobs().Informer(),
+	}
+	if w.options.ConfigWatch.Enabled {
+		configFactory, configWatched := configInformers(w.clientSet, resyncPeriod, w.options.ConfigWatch)
+		factories = append(factories, configFactory)
+		for kind, informer := range configWatched {
+			watched[kind] = informer
+		}
+	}
+	hpaInformer := factory.Autoscaling().V2().HorizontalPodAutoscalers()
+	autoscalers := autoscalerIndex{hpas: hpaInformer.Lister()}
+	hasSynced := []cache.InformerSynced{hpaInformer.Informer().HasSynced}
+	for kind, informer := range watched {
+		_, err := informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+			AddFunc: func(obj interface{}, isInInitialList bool) {
+				w.recordEvent()
+				w.handleAdd(obj, isInInitialList)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				w.recordEvent()
+				w.handleUpdate(oldObj, newObj, autoscalers)
+				rollouts.observe(oldObj, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				w.recordEvent()
+				w.handleDelete(obj)
+			},
+		})
+		if err != nil {
+			log.WithError(err).Errorf("Failed to register %s event handler", kind)
+			cancel()
+			return false
+		}
+		hasSynced = append(hasSynced, informer.HasSynced)
+	}
+
+	if w.options.PodFailure.Enabled {
+		podFailures := newPodFailureDetector(w.notifier, w.options.PodFailure)
+		podFactory, informer := podInformer(w.clientSet, resyncPeriod, w.options.PodFailure)
+		factories = append(factories, podFactory)
+		_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				w.recordEvent()
+				podFailures.observe(oldObj, newObj)
+			},
+		})
+		if err != nil {
+			log.WithError(err).Error("Failed to register Pod event handler")
+			cancel()
+			return false
+		}
+		hasSynced = append(hasSynced, informer.HasSynced)
+	}
+
+	if w.options.WarningEvents.Enabled {
+		informer, err := newWarningEventAggregator(ctx, w.clientSet, w.notifier, w.options.WarningEvents).informer(factory)
+		if err != nil {
+			log.WithError(err).Error("Failed to register Event event handler")
+			cancel()
+			return false
+		}
+		hasSynced = append(hasSynced, informer.HasSynced)
+	}
+
+	w.stopCh = make(chan struct{})
+	w.factories = factories
+	w.cancel = cancel
+	w.rollouts = rollouts
+	w.hasSynced = hasSynced
+	w.startedAt = time.Now()
+	w.eventsProcessed.Store(0)
+	w.lastEventAt.Store(0)
+
+	for _, f := range factories {
+		f.Start(w.stopCh)
+	}
+	log.Info("Change watcher started")
+	return true
+}
+
+// Stop 은 실행 중인 informer 를 멈추고, 실제로 멈췄는지 여부를 반환
+func (w *Watcher) Stop() bool {
+	w.mu.Lock()
+	if w.stopCh == nil {
+		w.mu.Unlock()
+		return false
+	}
+	close(w.stopCh)
+	// 진행 중인 rollout 추적도 함께 멈춘다
+	w.cancel()
+	factories := w.factories
+	w.stopCh = nil
+	w.factories = nil
+	w.rollouts = nil
+	w.hasSynced = nil
+	w.mu.Unlock()
+
+	// Shutdown 은 실행 중인 핸들러가 끝날 때까지 기다리므로 lock 을 잡지 않고 호출한다
+	for _, factory := range factories {
+		factory.Shutdown()
+	}
+	log.Info("Change watcher stopped")
+	return true
+}
+
+func (w *Watcher) Status() watcherStatusType {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	status := watcherStatusType{
+		Running:         w.stopCh != nil,
+		EventsProcessed: w.eventsProcessed.Load(),
+	}
+	if status.Running {
+		startedAt := w.startedAt
+		status.StartedAt = &startedAt
+		status.ActiveRollouts = w.rollouts.Active()
+		status.Synced = true
+		for _, synced := range w.hasSynced {
+			status.Synced = status.Synced && synced()
+		}
+	}
+	if last := w.lastEventAt.Load(); last > 0 {
+		lastEventAt := time.Unix(0, last)
+		status.LastEventAt = &lastEventAt
+	}
+	return status
+}
+
+func (w *Watcher) recordEvent() {
+	w.eventsProcessed.Add(1)
+	w.lastEventAt.Store(time.Now().UnixNano())
+}
@@ -0,0 +1,287 @@
+package cost
+
+import (
+	"client-go/internal/app/pod_metadata"
+	"client-go/internal/pkg/kube"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	GroupByNamespace = "namespace"
+	GroupByTeam      = "team"
+	GroupByWorkload  = "workload"
+	GroupByNodepool  = "nodepool"
+
+	idleKey       = "__idle__"
+	unlabeledKey  = "__unlabeled__"
+	hoursPerMonth = 730
+
+	defaultTeamLabel = "team"
+	defaultCPUWeight = 0.5
+)
+
+// Options 의 CPUWeight 는 노드 비용 중 CPU request 비율로 나눌 몫(나머지는 memory request 비율)
+type Options struct {
+	GroupBy   string
+	TeamLabel string
+	CPUWeight float64
+}
+
+type allocationType struct {
+	Key              string
+	Pods             int
+	CPURequestCores  float64
+	MemoryRequestGiB float64
+	HourlyCost       float64
+	MonthlyCost      float64
+	IdleHourlyCost   float64
+}
+
+// Report 는 groupBy 기준으로 묶은 비용 배분 결과로 report.Table 을 구현
+type Report struct {
+	GroupBy         string
+	TotalHourlyCost float64
+	UnpricedNodes   []string
+	Allocations     []allocationType
+}
+
+type nodeCostType struct {
+	hourlyPrice float64
+	cpu         float64
+	memory      float64
+	nodepool    string
+	totalShare  float64
+}
+
+type podShareType struct {
+	key    string
+	node   string
+	share  float64
+	cpu    float64
+	memory float64
+}
+
+// OptionsFromEnv 는 COST_TEAM_LABEL(기본 team), COST_CPU_WEIGHT(기본 0.5) 환경 변수로 기본 옵션을 만든다
+func OptionsFromEnv() Options {
+	opts := Options{GroupBy: GroupByNamespace, TeamLabel: defaultTeamLabel, CPUWeight: defaultCPUWeight}
+	if label := os.Getenv("COST_TEAM_LABEL"); label != "" {
+		opts.TeamLabel = label
+	}
+	if weight, err := strconv.ParseFloat(os.Getenv("COST_CPU_WEIGHT"), 64); err == nil && weight >= 0 && weight <= 1 {
+		opts.CPUWeight = weight
+	}
+	return opts
+}
+
+// Validate 는 요청으로 받을 수 있는 값(groupBy)을 검사한다
+func (o Options) Validate() error {
+	switch o.GroupBy {
+	case GroupByNamespace, GroupByTeam, GroupByWorkload, GroupByNodepool:
+		return nil
+	}
+	return fmt.Errorf("unsupported groupBy %q (available: %s, %s, %s, %s)", o.GroupBy, GroupByNamespace, GroupByTeam, GroupByWorkload, GroupByNodepool)
+}
+
+// GetCostAllocation 은 노드 시간당 비용을 파드의 request 비율로 나눠 groupBy 기준으로 합산한다.
+// 파드에 배분되지 않은 노드 비용은 idle 로 따로 집계한다.
+func GetCostAllocation(clientSet kubernetes.Interface, opts Options) (Report, error) {
+	if err := opts.Validate(); err != nil {
+		return Report{}, err
+	}
+
+	pricing, err := LoadPricing()
+	if err != nil {
+		return Report{}, err
+	}
+
+	ctx := context.Background()
+	costReport := Report{GroupBy: opts.GroupBy}
+
+	nodes := map[string]*nodeCostType{}
+	err = kube.EachNode(ctx, clientSet, metav1.ListOptions{}, func(node *coreV1.Node) error {
+		price, ok := pricing.HourlyPrice(node)
+		if !ok {
+			costReport.UnpricedNodes = append(costReport.UnpricedNodes, node.Name)
+		}
+		nodes[node.Name] = &nodeCostType{
+			hourlyPrice: price,
+			cpu:         node.Status.Allocatable.Cpu().AsApproximateFloat64(),
+			memory:      node.Status.Allocatable.Memory().AsApproximateFloat64(),
+			nodepool:    nodepool(node),
+		}
+		costReport.TotalHourlyCost += price
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to list nodes")
+		return Report{}, err
+	}
+
+	namespaceTeams := map[string]string{}
+	err = kube.EachNamespace(ctx, clientSet, metav1.ListOptions{}, func(ns *coreV1.Namespace) error {
+		namespaceTeams[ns.Name] = ns.Labels[opts.TeamLabel]
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to list namespaces")
+		return Report{}, err
+	}
+
+	var shares []podShareType
+	err = kube.EachPod(ctx, clientSet, "", metav1.ListOptions{
+		FieldSelector: "status.phase!=Succeeded,status.phase!=Failed",
+	}, func(pod *coreV1.Pod) error {
+		node, ok := nodes[pod.Spec.NodeName]
+		if !ok {
+			return nil
+		}
+		requests := pod_metadata.EffectiveRequests(pod.Spec)
+		cpu := requests.Cpu().AsApproximateFloat64()
+		memory := requests.Memory().AsApproximateFloat64()
+
+		share := opts.CPUWeight*ratio(cpu, node.cpu) + (1-opts.CPUWeight)*ratio(memory, node.memory)
+		node.totalShare += share
+
+		shares = append(shares, podShareType{
+			key:    groupKey(opts, pod, node, namespaceTeams),
+			node:   pod.Spec.NodeName,
+			share:  share,
+			cpu:    cpu,
+			memory: memory,
+		})
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to list pods")
+		return Report{}, err
+	}
+
+	allocations := map[string]*allocationType{}
+	allocation := func(key string) *allocationType {
+		if _, ok := allocations[key]; !ok {
+			allocations[key] = &allocationType{Key: key}
+		}
+		return allocations[key]
+	}
+
+	for _, ps := range shares {
+		node := nodes[ps.node]
+		share := ps.share
+		// request 합계가 allocatable 을 넘는 경우(overhead 등) 노드 비용 이상 배분하지 않도록 정규화
+		if node.totalShare > 1 {
+			share /= node.totalShare
+		}
+		a := allocation(ps.key)
+		a.Pods++
+		a.CPURequestCores += ps.cpu
+		a.MemoryRequestGiB += ps.memory / (1 << 30)
+		a.HourlyCost += node.hourlyPrice * share
+	}
+
+	for _, node := range nodes {
+		idle := node.hourlyPrice * math.Max(0, 1-node.totalShare)
+		if opts.GroupBy == GroupByNodepool {
+			// nodepool 기준일 때는 idle 비용도 해당 nodepool 에 포함
+			a := allocation(node.nodepool)
+			a.IdleHourlyCost += idle
+			a.HourlyCost += idle
+			continue
+		}
+		allocation(idleKey).HourlyCost += idle
+	}
+
+	for _, a := range allocations {
+		a.HourlyCost = round(a.HourlyCost)
+		a.IdleHourlyCost = round(a.IdleHourlyCost)
+		a.MonthlyCost = round(a.HourlyCost * hoursPerMonth)
+		a.CPURequestCores = round(a.CPURequestCores)
+		a.MemoryRequestGiB = round(a.MemoryRequestGiB)
+		costReport.Allocations = append(costReport.Allocations, *a)
+	}
+	sort.Slice(costReport.Allocations, func(i, j int) bool {
+		return costReport.Allocations[i].HourlyCost > costReport.Allocations[j].HourlyCost
+	})
+	costReport.TotalHourlyCost = round(costReport.TotalHourlyCost)
+
+	return costReport, nil
+}
+
+func groupKey(opts Options, pod *coreV1.Pod, node *nodeCostType, namespaceTeams map[string]string) string {
+	switch opts.GroupBy {
+	case GroupByTeam:
+		// 파드 라벨이 없으면 namespace 라벨을 사용
+		if team := pod.Labels[opts.TeamLabel]; team != "" {
+			return team
+		}
+		if team := namespaceTeams[pod.Namespace]; team != "" {
+			return team
+		}
+		return unlabeledKey
+	case GroupByWorkload:
+		kind, name := kube.OwnerWorkload(pod)
+		return fmt.Sprintf("%s/%s/%s", pod.Namespace, kind, name)
+	case GroupByNodepool:
+		return node.nodepool
+	default:
+		return pod.Namespace
+	}
+}
+
+func nodepool(node *coreV1.Node) string {
+	if pool := node.Labels["karpenter.sh/nodepool"]; pool != "" {
+		return pool
+	}
+	if group := node.Labels["eks.amazonaws.com/nodegroup"]; group != "" {
+		return group
+	}
+	return unlabeledKey
+}
+
+func ratio(value, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return value / total
+}
+
+func round(value float64) float64 {
+	return math.Round(value*10000) / 10000
+}
+
+func (r Report) Header() []string {
+	return []string{"Group By", "Key", "Pods", "CPU Request (cores)", "Memory Request (GiB)", "Hourly Cost", "Monthly Cost", "Idle Hourly Cost"}
+}
+
+func (r Report) Rows() [][]string {
+	rows := make([][]string, 0, len(r.Allocations))
+	for _, a := range r.Allocations {
+		rows = append(rows, []string{
+			r.GroupBy, a.Key, strconv.Itoa(a.Pods),
+			formatFloat(a.CPURequestCores), formatFloat(a.MemoryRequestGiB),
+			formatFloat(a.HourlyCost), formatFloat(a.MonthlyCost), formatFloat(a.IdleHourlyCost),
+		})
+	}
+	return rows
+}
+
+func (r Report) Records() []interface{} {
+	records := make([]interface{}, 0, len(r.Allocations))
+	for _, a := range r.Allocations {
+		records = append(records, a)
+	}
+	return records
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 4, 64)
+}
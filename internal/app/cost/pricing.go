@@ -0,0 +1,72 @@
+package cost
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	coreV1 "k8s.io/api/core/v1"
+)
+
+const (
+	CapacityOnDemand = "on-demand"
+	CapacitySpot     = "spot"
+)
+
+// Pricing 은 instance type -> capacity type -> 시간당 가격(USD)
+//
+//	{
+//	  "m5.large":  {"on-demand": 0.096, "spot": 0.035},
+//	  "c6i.xlarge": {"on-demand": 0.17}
+//	}
+type Pricing map[string]map[string]float64
+
+// LoadPricing 은 PRICING_FILE 환경 변수에 지정된 JSON 가격표를 읽는다
+func LoadPricing() (Pricing, error) {
+	path := os.Getenv("PRICING_FILE")
+	if path == "" {
+		return nil, errors.New("pricing file is not configured (set PRICING_FILE)")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read pricing file: %w", err)
+	}
+
+	pricing := Pricing{}
+	if err := json.Unmarshal(data, &pricing); err != nil {
+		return nil, fmt.Errorf("invalid pricing file %s: %w", path, err)
+	}
+	return pricing, nil
+}
+
+// HourlyPrice 는 노드의 시간당 가격을 반환한다. 가격표에 없는 노드는 false
+func (p Pricing) HourlyPrice(node *coreV1.Node) (float64, bool) {
+	prices, ok := p[instanceType(node)]
+	if !ok {
+		return 0, false
+	}
+	price, ok := prices[capacityType(node)]
+	return price, ok
+}
+
+func instanceType(node *coreV1.Node) string {
+	if instanceType := node.Labels["node.kubernetes.io/instance-type"]; instanceType != "" {
+		return instanceType
+	}
+	return node.Labels["beta.kubernetes.io/instance-type"]
+}
+
+// capacityType 은 karpenter 와 EKS managed node group 의 capacity type 라벨을 같은 값으로 맞춘다
+func capacityType(node *coreV1.Node) string {
+	capacity := node.Labels["karpenter.sh/capacity-type"]
+	if capacity == "" {
+		capacity = node.Labels["eks.amazonaws.com/capacityType"]
+	}
+	capacity = strings.ReplaceAll(strings.ToLower(capacity), "_", "-")
+	if capacity == "" {
+		return CapacityOnDemand
+	}
+	return capacity
+}
@@ -1,67 +1,180 @@
 package evictedpod
 
 import (
+	"client-go/internal/pkg/kube"
 	"context"
 	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
 	log "github.com/sirupsen/logrus"
 	coreV1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"os"
-	"sync"
-	"time"
+	"k8s.io/client-go/util/flowcontrol"
 )
 
-func EvictedPods(clientSet *kubernetes.Clientset) ([]string, error) {
+const (
+	PodDeleted     = "deleted"
+	PodAlreadyGone = "already-gone"
+	PodFailed      = "failed"
+	PodPending     = "pending"
+
+	defaultWorkers = 5
+	defaultQPS     = 3 // 기존 300ms 간격과 비슷한 속도
+)
+
+// DeleteOptions 는 evicted pod 삭제 시 동시에 실행할 worker 수와 초당 삭제 요청 수를 지정
+type DeleteOptions struct {
+	Workers int
+	QPS     float32
+}
+
+type evictedPodResultType struct {
+	Namespace string
+	PodName   string
+	Status    string
+	Error     string
+}
+
+type evictedPodsSummaryType struct {
+	Total       int
+	Deleted     int
+	AlreadyGone int
+	Failed      int
+	Pods        []evictedPodResultType
+}
+
+// OptionsFromEnv 는 EVICTED_POD_WORKERS, EVICTED_POD_QPS 환경 변수로 DeleteOptions 를 만든다.
+// 값이 없거나 잘못된 경우 기본값을 사용
+func OptionsFromEnv() DeleteOptions {
+	opts := DeleteOptions{Workers: defaultWorkers, QPS: defaultQPS}
+	if workers, err := strconv.Atoi(os.Getenv("EVICTED_POD_WORKERS")); err == nil && workers > 0 {
+		opts.Workers = workers
+	}
+	if qps, err := strconv.ParseFloat(os.Getenv("EVICTED_POD_QPS"), 32); err == nil && qps > 0 {
+		opts.QPS = float32(qps)
+	}
+	return opts
+}
+
+func EvictedPods(clientSet *kubernetes.Clientset, opts DeleteOptions) (evictedPodsSummaryType, error) {
 	evictedPods, err := listEvictedPods(clientSet)
 	if err != nil {
 		log.WithError(err).Error("Failed to list evicted pods")
-		return nil, err
+		return evictedPodsSummaryType{}, err
 	}
 
-	var wg sync.WaitGroup
-	deletedPods := make([]string, 0, len(evictedPods))
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.QPS <= 0 {
+		opts.QPS = defaultQPS
+	}
 
-	// Rate limiting setup
-	rateLimiter := time.Tick(time.Millisecond * 300) // 300ms
+	// worker 들이 같은 rate limiter 를 공유하므로 worker 수와 상관없이 QPS 를 넘지 않는다
+	rateLimiter := flowcontrol.NewTokenBucketRateLimiter(opts.QPS, 1)
+	defer rateLimiter.Stop()
 
-	for _, pod := range evictedPods {
-		<-rateLimiter // Wait for the next tick
+	results := make([]evictedPodResultType, len(evictedPods))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Workers; w++ {
 		wg.Add(1)
-		deletedPods = append(deletedPods, pod.Name)
-		go deletePod(clientSet, pod, &wg)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				rateLimiter.Accept()
+				results[i] = deletePod(clientSet, evictedPods[i])
+			}
+		}()
 	}
+
+	for i := range evictedPods {
+		jobs <- i
+	}
+	close(jobs)
 	wg.Wait()
 
-	return deletedPods, nil
+	return summarize(results), nil
 }
 
-func listEvictedPods(clientSet *kubernetes.Clientset) ([]coreV1.Pod, error) {
-	evictedPods, err := clientSet.CoreV1().Pods("").List(context.TODO(), v1.ListOptions{
-		FieldSelector: "status.phase=Failed",
-	})
+// PreviewEvictedPods 는 삭제하지 않고 EvictedPods 가 삭제할 pod 목록만 반환한다
+func PreviewEvictedPods(clientSet *kubernetes.Clientset) (evictedPodsSummaryType, error) {
+	evictedPods, err := listEvictedPods(clientSet)
 	if err != nil {
-		return nil, err
+		log.WithError(err).Error("Failed to list evicted pods")
+		return evictedPodsSummaryType{}, err
 	}
 
+	results := make([]evictedPodResultType, 0, len(evictedPods))
+	for _, pod := range evictedPods {
+		results = append(results, evictedPodResultType{
+			Namespace: pod.Namespace,
+			PodName:   pod.Name,
+			Status:    PodPending,
+		})
+	}
+	return summarize(results), nil
+}
+
+func listEvictedPods(clientSet *kubernetes.Clientset) ([]coreV1.Pod, error) {
 	var filteredPods []coreV1.Pod
-	for _, pod := range evictedPods.Items {
-		if pod.Status.Reason == "Evicted" && isPodDeletable(pod) {
+	err := kube.EachPod(context.TODO(), clientSet, "", v1.ListOptions{
+		FieldSelector: "status.phase=Failed",
+	}, func(pod *coreV1.Pod) error {
+		if pod.Status.Reason == "Evicted" && isPodDeletable(*pod) {
 			log.Info("Found evicted pod: ", pod.Name)
-			filteredPods = append(filteredPods, pod)
+			filteredPods = append(filteredPods, *pod)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return filteredPods, nil
 }
 
-func deletePod(clientSet *kubernetes.Clientset, pod coreV1.Pod, wg *sync.WaitGroup) {
-	defer wg.Done()
+func deletePod(clientSet *kubernetes.Clientset, pod coreV1.Pod) evictedPodResultType {
+	result := evictedPodResultType{
+		Namespace: pod.Namespace,
+		PodName:   pod.Name,
+		Status:    PodDeleted,
+	}
+
 	err := clientSet.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name, v1.DeleteOptions{})
-	if err != nil && !errors.IsNotFound(err) {
+	if err != nil {
+		if errors.IsNotFound(err) {
+			result.Status = PodAlreadyGone
+			return result
+		}
 		log.WithError(err).Error(fmt.Sprintf("Error deleting pod %s", pod.Name))
+		result.Status = PodFailed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func summarize(results []evictedPodResultType) evictedPodsSummaryType {
+	summary := evictedPodsSummaryType{
+		Total: len(results),
+		Pods:  results,
+	}
+	for _, result := range results {
+		switch result.Status {
+		case PodDeleted:
+			summary.Deleted++
+		case PodAlreadyGone:
+			summary.AlreadyGone++
+		case PodFailed:
+			summary.Failed++
+		}
 	}
+	return summary
 }
 
 func isPodDeletable(pod coreV1.Pod) bool {
@@ -0,0 +1,195 @@
+package lint
+
+import (
+	"client-go/internal/app/pod_metadata"
+	"client-go/internal/pkg/image"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrInvalidOptions 는 요청으로 받은 rule 이나 severity 가 잘못된 경우. errors.Is 로 구분한다
+var ErrInvalidOptions = errors.New("invalid lint options")
+
+// Options 는 요청마다 바꿀 수 있는 lint 대상과 결과 필터
+type Options struct {
+	Scope       pod_metadata.NamespaceScope
+	MinSeverity string
+	Rules       []string
+}
+
+type findingType struct {
+	Rule         string
+	Severity     string
+	WorkloadKind string
+	WorkloadName string
+	Container    string
+	Message      string
+}
+
+type namespaceFindingsType struct {
+	Namespace string
+	Findings  []findingType
+}
+
+type Result struct {
+	Workloads  int
+	Summary    map[string]int
+	Namespaces []namespaceFindingsType
+}
+
+func Lint(clientSet kubernetes.Interface, config Config, opts Options) (Result, error) {
+	if opts.MinSeverity == "" {
+		opts.MinSeverity = SeverityInfo
+	}
+	if _, ok := severityRank[opts.MinSeverity]; !ok {
+		return Result{}, fmt.Errorf("%w: invalid severity %q", ErrInvalidOptions, opts.MinSeverity)
+	}
+	if len(opts.Rules) > 0 {
+		selected := map[string]bool{}
+		for _, rule := range opts.Rules {
+			if _, ok := config.Rules[rule]; !ok {
+				return Result{}, fmt.Errorf("%w: unknown lint rule %q", ErrInvalidOptions, rule)
+			}
+			selected[rule] = true
+		}
+		for name, rule := range config.Rules {
+			rule.Disabled = rule.Disabled || !selected[name]
+			config.Rules[name] = rule
+		}
+	}
+
+	productionScope, err := pod_metadata.ScopePreset(config.ProductionScope)
+	if err != nil {
+		return Result{}, err
+	}
+	isProduction, err := productionScope.NameMatcher()
+	if err != nil {
+		return Result{}, err
+	}
+
+	workloads, err := pod_metadata.ListScopedWorkloads(context.Background(), clientSet, opts.Scope)
+	if err != nil {
+		log.WithError(err).Error("Failed to list workloads")
+		return Result{}, err
+	}
+
+	excluded := map[string]bool{}
+	for _, name := range config.ExcludedContainers {
+		excluded[name] = true
+	}
+
+	result := Result{
+		Workloads: len(workloads),
+		Summary:   map[string]int{SeverityInfo: 0, SeverityWarning: 0, SeverityError: 0},
+	}
+	byNamespace := map[string][]findingType{}
+	for _, w := range workloads {
+		for _, finding := range lintWorkload(w, config, isProduction(w.Namespace), excluded) {
+			if severityRank[finding.Severity] < severityRank[opts.MinSeverity] {
+				continue
+			}
+			result.Summary[finding.Severity]++
+			byNamespace[w.Namespace] = append(byNamespace[w.Namespace], finding)
+		}
+	}
+
+	for namespace, findings := range byNamespace {
+		// 심각한 항목이 먼저 보이도록 정렬
+		sort.SliceStable(findings, func(i, j int) bool {
+			return severityRank[findings[i].Severity] > severityRank[findings[j].Severity]
+		})
+		result.Namespaces = append(result.Namespaces, namespaceFindingsType{Namespace: namespace, Findings: findings})
+	}
+	sort.Slice(result.Namespaces, func(i, j int) bool {
+		return result.Namespaces[i].Namespace < result.Namespaces[j].Namespace
+	})
+	return result, nil
+}
+
+func lintWorkload(w pod_metadata.Workload, config Config, production bool, excluded map[string]bool) []findingType {
+	var findings []findingType
+	report := func(rule, container, format string, args ...interface{}) {
+		if !config.enabled(rule) {
+			return
+		}
+		findings = append(findings, findingType{
+			Rule:         rule,
+			Severity:     config.severity(rule),
+			WorkloadKind: w.Kind,
+			WorkloadName: w.Name,
+			Container:    container,
+			Message:      fmt.Sprintf(format, args...),
+		})
+	}
+
+	if production && w.Replicas == 1 && (w.Kind == pod_metadata.KindDeployment || w.Kind == pod_metadata.KindStatefulSet) {
+		report(RuleSingleReplicaProduction, "", "%s runs a single replica in a production namespace", w.Kind)
+	}
+
+	// Job/CronJob 은 끝나는 워크로드라 probe 를 검사하지 않는다
+	longRunning := w.Kind != pod_metadata.KindJob && w.Kind != pod_metadata.KindCronJob
+
+	containers := append(append([]corev1.Container{}, w.Template.Spec.InitContainers...), w.Template.Spec.Containers...)
+	for i, container := range containers {
+		if excluded[container.Name] {
+			continue
+		}
+		isInit := i < len(w.Template.Spec.InitContainers)
+		lintResources(container, config, report)
+
+		if ref := image.Parse(container.Image); ref.IsFloating() {
+			report(RuleFloatingImageTag, container.Name, "image %s is untagged or uses :latest", container.Image)
+		}
+
+		if longRunning && !isInit {
+			if container.ReadinessProbe == nil {
+				report(RuleMissingReadinessProbe, container.Name, "readiness probe is not defined")
+			}
+			if container.LivenessProbe == nil {
+				report(RuleMissingLivenessProbe, container.Name, "liveness probe is not defined")
+			}
+		}
+	}
+	return findings
+}
+
+func lintResources(container corev1.Container, config Config, report func(rule, container, format string, args ...interface{})) {
+	requests := container.Resources.Requests
+	limits := container.Resources.Limits
+
+	_, hasCPURequest := requests[corev1.ResourceCPU]
+	memoryRequest, hasMemoryRequest := requests[corev1.ResourceMemory]
+	memoryLimit, hasMemoryLimit := limits[corev1.ResourceMemory]
+
+	// limit 만 지정하면 request 는 limit 과 같은 값이 되므로 누락으로 보지 않는다
+	if _, hasCPULimit := limits[corev1.ResourceCPU]; !hasCPURequest && !hasCPULimit {
+		report(RuleMissingCPURequest, container.Name, "cpu request is not set")
+	}
+	if !hasMemoryRequest && !hasMemoryLimit {
+		report(RuleMissingMemoryRequest, container.Name, "memory request is not set")
+	}
+	if !hasMemoryLimit {
+		report(RuleMissingMemoryLimit, container.Name, "memory limit is not set")
+	} else if hasMemoryRequest && memoryLimit.Cmp(memoryRequest) < 0 {
+		report(RuleMemoryLimitBelowRequest, container.Name, "memory limit %s is below request %s", memoryLimit.String(), memoryRequest.String())
+	}
+
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		req, hasRequest := requests[name]
+		limit, hasLimit := limits[name]
+		if !hasRequest || !hasLimit {
+			continue
+		}
+		if reqValue := req.AsApproximateFloat64(); reqValue > 0 {
+			if ratio := limit.AsApproximateFloat64() / reqValue; ratio > config.MaxLimitRequestRatio {
+				report(RuleLimitRequestRatio, container.Name, "%s limit/request ratio %.1f exceeds %.1f (%s / %s)", name, ratio, config.MaxLimitRequestRatio, limit.String(), req.String())
+			}
+		}
+	}
+}
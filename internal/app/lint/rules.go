@@ -0,0 +1,121 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+const (
+	RuleMissingCPURequest       = "missing-cpu-request"
+	RuleMissingMemoryRequest    = "missing-memory-request"
+	RuleMissingMemoryLimit      = "missing-memory-limit"
+	RuleMemoryLimitBelowRequest = "memory-limit-below-request"
+	RuleLimitRequestRatio       = "limit-request-ratio"
+	RuleMissingReadinessProbe   = "missing-readiness-probe"
+	RuleMissingLivenessProbe    = "missing-liveness-probe"
+	RuleFloatingImageTag        = "floating-image-tag"
+	RuleSingleReplicaProduction = "single-replica-production"
+)
+
+var severityRank = map[string]int{
+	SeverityInfo:    0,
+	SeverityWarning: 1,
+	SeverityError:   2,
+}
+
+type RuleConfig struct {
+	Disabled bool
+	Severity string
+}
+
+// Config 는 LINT_RULES_FILE(JSON) 로 덮어쓸 수 있는 lint 설정
+//
+//	{
+//	  "MaxLimitRequestRatio": 3,
+//	  "ProductionScope": "product",
+//	  "Rules": {"missing-liveness-probe": {"Disabled": true}, "missing-cpu-request": {"Severity": "error"}}
+//	}
+type Config struct {
+	MaxLimitRequestRatio float64
+	// ProductionScope 는 replicas=1 을 검사할 namespace scope preset 이름
+	ProductionScope    string
+	ExcludedContainers []string
+	Rules              map[string]RuleConfig
+}
+
+func defaultConfig() Config {
+	return Config{
+		MaxLimitRequestRatio: 4,
+		ProductionScope:      "product",
+		Rules: map[string]RuleConfig{
+			RuleMissingCPURequest:       {Severity: SeverityWarning},
+			RuleMissingMemoryRequest:    {Severity: SeverityWarning},
+			RuleMissingMemoryLimit:      {Severity: SeverityError},
+			RuleMemoryLimitBelowRequest: {Severity: SeverityError},
+			RuleLimitRequestRatio:       {Severity: SeverityWarning},
+			RuleMissingReadinessProbe:   {Severity: SeverityWarning},
+			RuleMissingLivenessProbe:    {Severity: SeverityInfo},
+			RuleFloatingImageTag:        {Severity: SeverityError},
+			RuleSingleReplicaProduction: {Severity: SeverityWarning},
+		},
+	}
+}
+
+// ConfigFromEnv 는 기본 설정에 LINT_RULES_FILE 의 값을 덮어쓴다
+func ConfigFromEnv() (Config, error) {
+	config := defaultConfig()
+	path := os.Getenv("LINT_RULES_FILE")
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("could not read lint rules file: %w", err)
+	}
+	var override Config
+	if err := json.Unmarshal(data, &override); err != nil {
+		return config, fmt.Errorf("invalid lint rules file %s: %w", path, err)
+	}
+
+	if override.MaxLimitRequestRatio > 0 {
+		config.MaxLimitRequestRatio = override.MaxLimitRequestRatio
+	}
+	if override.ProductionScope != "" {
+		config.ProductionScope = override.ProductionScope
+	}
+	if len(override.ExcludedContainers) > 0 {
+		config.ExcludedContainers = override.ExcludedContainers
+	}
+	for name, rule := range override.Rules {
+		base, ok := config.Rules[name]
+		if !ok {
+			return config, fmt.Errorf("unknown lint rule %q", name)
+		}
+		base.Disabled = rule.Disabled
+		if rule.Severity != "" {
+			if _, ok := severityRank[rule.Severity]; !ok {
+				return config, fmt.Errorf("invalid severity %q for rule %s", rule.Severity, name)
+			}
+			base.Severity = rule.Severity
+		}
+		config.Rules[name] = base
+	}
+	return config, nil
+}
+
+func (c Config) enabled(rule string) bool {
+	r, ok := c.Rules[rule]
+	return ok && !r.Disabled
+}
+
+func (c Config) severity(rule string) string {
+	return c.Rules[rule].Severity
+}
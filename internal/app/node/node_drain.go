@@ -1,6 +1,7 @@
 package node
 
 import (
+	"client-go/internal/pkg/kube"
 	"context"
 	"fmt"
 	"os"
@@ -35,105 +36,81 @@ func NodeDrain(clientSet *kubernetes.Clientset, percentage string, dryRun string
 		return nil, err
 	}
 
-	nodes, err := clientSet.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	// 전체 노드 목록을 들고 있지 않도록 페이지를 넘기면서 drain 대상만 남긴다
+	drainNodeLabels := strings.Split(os.Getenv("DRAIN_NODE_LABELS"), ",")
+	var targets []dryRunResult
+	err = kube.EachNode(context.Background(), clientSet, metav1.ListOptions{}, func(node *coreV1.Node) error {
+		targets = append(targets, drainTargets(node, overNodes, drainNodeLabels)...)
+		return nil
+	})
 	if err != nil {
 		log.WithError(err).Error("Failed to list nodes")
 		return nil, err
 	}
 
 	if dryRun == "true" {
-		return handleDryRun(nodes, overNodes), nil
+		log.Info("Dry run mode enabled")
+		return targets, nil
 	} else if dryRun == "false" {
-		if err := cordonNodes(clientSet, nodes, overNodes); err != nil {
+		if err := cordonNodes(clientSet, targets); err != nil {
 			return nil, err
 		}
-		return handleDrain(clientSet, nodes, overNodes)
+		return nil, handleDrain(clientSet, targets)
 	}
 
 	return nil, nil
 }
 
-func handleDryRun(nodes *coreV1.NodeList, overNodes []NodeMemoryUsageType) []dryRunResult {
-	drainNodeLabels := strings.Split(os.Getenv("DRAIN_NODE_LABELS"), ",")
-	var dryRunResults []dryRunResult
-	log.Info("Dry run mode enabled")
-	for _, node := range nodes.Items {
-		for _, overNode := range overNodes {
-			provisionerName := node.Labels["karpenter.sh/nodepool"]
-			if strings.Contains(node.Annotations["alpha.kubernetes.io/provided-node-ip"], overNode.NodeName) {
-				for _, label := range drainNodeLabels {
-					if strings.TrimSpace(provisionerName) == strings.TrimSpace(label) {
-						dryRunResults = append(dryRunResults, dryRunResult{
-							NodeName:        node.Name,
-							InstanceType:    node.Labels["beta.kubernetes.io/instance-type"],
-							ProvisionerName: provisionerName,
-							Percentage:      overNode.MemoryUsage,
-						})
-					}
-				}
-			}
+// drainTargets 는 노드가 메모리 사용률 조건에 걸리고 DRAIN_NODE_LABELS 의 nodepool 에 속하면 drain 대상으로 반환
+func drainTargets(node *coreV1.Node, overNodes []NodeMemoryUsageType, drainNodeLabels []string) []dryRunResult {
+	// node_kind 혹은 karpenter.sh/nodepool 으로 변경(karpenter 0.32+ 에서는 karpenter.sh/provisioner-name label 제거 되고 karpenter.sh/nodepool 로 변경됐습니다.)
+	provisionerName := node.Labels["karpenter.sh/nodepool"]
+	if !matchesDrainLabel(provisionerName, drainNodeLabels) {
+		return nil
+	}
+
+	var targets []dryRunResult
+	for _, overNode := range overNodes {
+		if strings.Contains(node.Annotations["alpha.kubernetes.io/provided-node-ip"], overNode.NodeName) {
+			targets = append(targets, dryRunResult{
+				NodeName:        node.Name,
+				InstanceType:    node.Labels["beta.kubernetes.io/instance-type"],
+				ProvisionerName: provisionerName,
+				Percentage:      overNode.MemoryUsage,
+			})
 		}
 	}
-	return dryRunResults
+	return targets
 }
 
-func cordonNodes(clientSet *kubernetes.Clientset, nodes *coreV1.NodeList, overNodes []NodeMemoryUsageType) error {
-	// DRAIN_NODE_LABELS 환경 변수를 쉼표로 구분하여 배열로 변환
-	drainNodeLabels := strings.Split(os.Getenv("DRAIN_NODE_LABELS"), ",")
-	log.Info(drainNodeLabels)
-	for _, node := range nodes.Items {
-		if err := checkOverNode(clientSet, node, overNodes, drainNodeLabels); err != nil {
-			return err
+func matchesDrainLabel(provisionerName string, drainNodeLabels []string) bool {
+	for _, label := range drainNodeLabels {
+		if strings.TrimSpace(provisionerName) == strings.TrimSpace(label) {
+			return true
 		}
 	}
-	return nil
+	return false
 }
 
-func checkOverNode(clientSet *kubernetes.Clientset, node coreV1.Node, overNodes []NodeMemoryUsageType, drainNodeLabels []string) error {
-	for _, overNode := range overNodes {
-		provisionerName := node.Labels["karpenter.sh/nodepool"]
-		if strings.Contains(node.Annotations["alpha.kubernetes.io/provided-node-ip"], overNode.NodeName) {
-			for _, label := range drainNodeLabels {
-				if strings.TrimSpace(provisionerName) == strings.TrimSpace(label) {
-					if err := cordonNode(clientSet, node.Name); err != nil {
-						log.WithError(err).Error("Failed to cordon node ", node.Name)
-						return err
-					}
-				}
-			}
+func cordonNodes(clientSet *kubernetes.Clientset, targets []dryRunResult) error {
+	for _, target := range targets {
+		if err := cordonNode(clientSet, target.NodeName); err != nil {
+			log.WithError(err).Error("Failed to cordon node ", target.NodeName)
+			return err
 		}
 	}
 	return nil
 }
 
-func handleDrain(clientSet *kubernetes.Clientset, nodes *coreV1.NodeList, overNodes []NodeMemoryUsageType) ([]dryRunResult, error) {
-	drainNodeLabels := strings.Split(os.Getenv("DRAIN_NODE_LABELS"), ",")
+func handleDrain(clientSet *kubernetes.Clientset, targets []dryRunResult) error {
 	// 메모리 사용률 기준으로 정렬
-	sort.Slice(overNodes, func(i, j int) bool {
-		return overNodes[i].MemoryUsage < overNodes[j].MemoryUsage
+	sort.SliceStable(targets, func(i, j int) bool {
+		return targets[i].Percentage < targets[j].Percentage
 	})
 
-	for _, overNode := range overNodes {
-		if err := drainMatchingNodes(clientSet, nodes, overNode, drainNodeLabels); err != nil {
-			return nil, err
-		}
-	}
-
-	return nil, nil
-}
-
-func drainMatchingNodes(clientSet *kubernetes.Clientset, nodes *coreV1.NodeList, overNode NodeMemoryUsageType, drainNodeLabels []string) error {
-	for _, node := range nodes.Items {
-		// node_kind 혹은 karpenter.sh/nodepool 으로 변경(karpenter 0.32+ 에서는 karpenter.sh/provisioner-name label 제거 되고 karpenter.sh/nodepool 로 변경됐습니다.)
-		provisionerName := node.Labels["karpenter.sh/nodepool"]
-		if strings.Contains(node.Annotations["alpha.kubernetes.io/provided-node-ip"], overNode.NodeName) {
-			for _, label := range drainNodeLabels {
-				if strings.TrimSpace(provisionerName) == strings.TrimSpace(label) {
-					if err := drainSingleNode(clientSet, node.Name); err != nil {
-						return err
-					}
-				}
-			}
+	for _, target := range targets {
+		if err := drainSingleNode(clientSet, target.NodeName); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -243,20 +220,19 @@ func waitForPodsToTerminate(clientSet kubernetes.Interface, nodeName string) err
 }
 
 func getNonCriticalPods(clientSet kubernetes.Interface, nodeName string) ([]coreV1.Pod, error) {
-	podList, err := clientSet.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{
+	var pods []coreV1.Pod
+	err := kube.EachPod(context.Background(), clientSet, "", metav1.ListOptions{
 		FieldSelector: fmt.Sprintf("spec.nodeName=%s,status.phase!=Succeeded,status.phase!=Failed", nodeName),
+	}, func(pod *coreV1.Pod) error {
+		if !isManagedByDaemonSet(*pod) {
+			pods = append(pods, *pod)
+		}
+		return nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list pods on node %s: %v", nodeName, err)
 	}
 
-	var pods []coreV1.Pod
-	for _, pod := range podList.Items {
-		if !isManagedByDaemonSet(pod) {
-			pods = append(pods, pod)
-		}
-	}
-
 	return pods, nil
 }
 
@@ -1,10 +1,12 @@
 package node
 
 import (
+	"client-go/internal/pkg/kube"
 	"context"
 	"fmt"
 	"os"
 
+	coreV1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -16,26 +18,26 @@ type nodePodCountType struct {
 
 func GetNodePodUsageByLabel(clientSet *kubernetes.Clientset) ([]nodePodCountType, error) {
 	labelSelector := fmt.Sprintf("karpenter.sh/provisioner-name=%s", os.Getenv("DRAIN_NODE_LABELS_1"))
-	nodes, err := clientSet.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{
-		LabelSelector: labelSelector,
-	})
-	if err != nil {
-		return nil, err
-	}
-
 	var nodePodUsages []nodePodCountType
-	for _, node := range nodes.Items {
-		pods, err := clientSet.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{
+	err := kube.EachNode(context.Background(), clientSet, metav1.ListOptions{
+		LabelSelector: labelSelector,
+	}, func(node *coreV1.Node) error {
+		// 파드 목록을 보관하지 않고 페이지 단위로 개수만 센다
+		podCount, err := kube.CountPods(context.Background(), clientSet, "", metav1.ListOptions{
 			FieldSelector: "spec.nodeName=" + node.Name,
 		})
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		nodePodUsages = append(nodePodUsages, nodePodCountType{
 			NodeName: node.Name,
-			PodCount: len(pods.Items),
+			PodCount: podCount,
 		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return nodePodUsages, nil
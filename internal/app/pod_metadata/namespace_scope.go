@@ -0,0 +1,197 @@
+package pod_metadata
+
+import (
+	"client-go/internal/pkg/kube"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const defaultScopePreset = "product"
+
+// NamespaceScope 는 리포트 대상 namespace 를 고르는 조건.
+// LabelSelector 는 항상 적용되고, Prefixes/Regexes/Names 중 하나라도 지정되면 그중 하나에 맞는 namespace 만 포함한다.
+// 아무 조건도 없으면 모든 namespace 가 대상이다.
+type NamespaceScope struct {
+	Prefixes      []string
+	Regexes       []string
+	Names         []string
+	LabelSelector string
+}
+
+// 기본 제공 preset. REPORT_NAMESPACE_PRESETS 환경 변수(JSON)로 추가하거나 덮어쓸 수 있다.
+// 예) {"team-a": {"Prefixes": ["team-a-"]}, "infra": {"LabelSelector": "tier=infra"}}
+var builtinScopePresets = map[string]NamespaceScope{
+	"product": {Prefixes: []string{"prd-", "product-"}},
+	"all":     {},
+}
+
+func scopePresets() (map[string]NamespaceScope, error) {
+	presets := make(map[string]NamespaceScope, len(builtinScopePresets))
+	for name, scope := range builtinScopePresets {
+		presets[name] = scope
+	}
+	if raw := os.Getenv("REPORT_NAMESPACE_PRESETS"); raw != "" {
+		custom := map[string]NamespaceScope{}
+		if err := json.Unmarshal([]byte(raw), &custom); err != nil {
+			return nil, fmt.Errorf("invalid REPORT_NAMESPACE_PRESETS: %w", err)
+		}
+		for name, scope := range custom {
+			presets[name] = scope
+		}
+	}
+	return presets, nil
+}
+
+// ScopePresetNames 는 사용할 수 있는 preset 이름 목록을 반환
+func ScopePresetNames() []string {
+	presets, err := scopePresets()
+	if err != nil {
+		presets = builtinScopePresets
+	}
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func ScopePreset(name string) (NamespaceScope, error) {
+	presets, err := scopePresets()
+	if err != nil {
+		return NamespaceScope{}, err
+	}
+	scope, ok := presets[name]
+	if !ok {
+		return NamespaceScope{}, fmt.Errorf("unknown namespace scope preset %q (available: %s)", name, strings.Join(ScopePresetNames(), ", "))
+	}
+	return scope, nil
+}
+
+// ScopeFromEnv 는 REPORT_NAMESPACE_SCOPE preset(기본값 product) 을 기준으로
+// REPORT_NAMESPACE_PREFIXES, REPORT_NAMESPACE_REGEXES, REPORT_NAMESPACES, REPORT_NAMESPACE_SELECTOR 로 조건을 덮어쓴다
+func ScopeFromEnv() (NamespaceScope, error) {
+	preset := os.Getenv("REPORT_NAMESPACE_SCOPE")
+	if preset == "" {
+		preset = defaultScopePreset
+	}
+	scope, err := ScopePreset(preset)
+	if err != nil {
+		return NamespaceScope{}, err
+	}
+
+	custom := NamespaceScope{
+		Prefixes:      SplitList(os.Getenv("REPORT_NAMESPACE_PREFIXES")),
+		Regexes:       SplitList(os.Getenv("REPORT_NAMESPACE_REGEXES")),
+		Names:         SplitList(os.Getenv("REPORT_NAMESPACES")),
+		LabelSelector: os.Getenv("REPORT_NAMESPACE_SELECTOR"),
+	}
+	if !custom.IsEmpty() {
+		scope = custom
+	}
+	return scope, scope.Validate()
+}
+
+func (s NamespaceScope) IsEmpty() bool {
+	return len(s.Prefixes) == 0 && len(s.Regexes) == 0 && len(s.Names) == 0 && s.LabelSelector == ""
+}
+
+// Equal 은 두 scope 의 조건이 같은지 비교한다
+func (s NamespaceScope) Equal(other NamespaceScope) bool {
+	return slices.Equal(s.Prefixes, other.Prefixes) && slices.Equal(s.Regexes, other.Regexes) &&
+		slices.Equal(s.Names, other.Names) && s.LabelSelector == other.LabelSelector
+}
+
+func (s NamespaceScope) Validate() error {
+	_, err := s.compile()
+	return err
+}
+
+type namespaceMatcher struct {
+	scope   NamespaceScope
+	regexes []*regexp.Regexp
+}
+
+func (s NamespaceScope) compile() (*namespaceMatcher, error) {
+	matcher := &namespaceMatcher{scope: s}
+	for _, expr := range s.Regexes {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace regex %q: %w", expr, err)
+		}
+		matcher.regexes = append(matcher.regexes, re)
+	}
+	if s.LabelSelector != "" {
+		if _, err := labels.Parse(s.LabelSelector); err != nil {
+			return nil, fmt.Errorf("invalid namespace label selector %q: %w", s.LabelSelector, err)
+		}
+	}
+	return matcher, nil
+}
+
+func (m *namespaceMatcher) matches(name string) bool {
+	if len(m.scope.Prefixes) == 0 && len(m.regexes) == 0 && len(m.scope.Names) == 0 {
+		return true
+	}
+	for _, prefix := range m.scope.Prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	for _, re := range m.regexes {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	for _, ns := range m.scope.Names {
+		if name == ns {
+			return true
+		}
+	}
+	return false
+}
+
+// NameMatcher 는 namespace 이름만으로 scope 를 판단하는 함수를 반환한다(LabelSelector 는 무시)
+func (s NamespaceScope) NameMatcher() (func(name string) bool, error) {
+	matcher, err := s.compile()
+	if err != nil {
+		return nil, err
+	}
+	return matcher.matches, nil
+}
+
+// EachScopedNamespace 는 scope 에 맞는 namespace 마다 fn 을 호출한다. label selector 는 API 서버에서 필터링.
+// fn 이 오래 걸려도 namespace 목록의 continue token 이 만료되지 않도록 이름을 먼저 모두 모은 뒤 호출한다
+func EachScopedNamespace(ctx context.Context, clientset kubernetes.Interface, scope NamespaceScope, fn func(namespace string) error) error {
+	matcher, err := scope.compile()
+	if err != nil {
+		return err
+	}
+	var namespaces []string
+	err = kube.EachNamespace(ctx, clientset, metav1.ListOptions{LabelSelector: scope.LabelSelector}, func(ns *corev1.Namespace) error {
+		if matcher.matches(ns.Name) {
+			namespaces = append(namespaces, ns.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, namespace := range namespaces {
+		if err := fn(namespace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
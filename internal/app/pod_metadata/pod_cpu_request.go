@@ -2,95 +2,148 @@ package pod_metadata
 
 import (
 	"context"
-	"encoding/csv"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
-func listDeployments(clientset *kubernetes.Clientset) ([]appsv1.Deployment, []string, error) {
-	namespaces, err := clientset.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
+// ListScopedWorkloads 는 scope 에 맞는 namespace 의 모든 워크로드를 가져온다
+func ListScopedWorkloads(ctx context.Context, clientset kubernetes.Interface, scope NamespaceScope) ([]Workload, error) {
+	var workloads []Workload
+	err := EachScopedWorkload(ctx, clientset, scope, func(w *Workload) error {
+		workloads = append(workloads, *w)
+		return nil
+	})
 	if err != nil {
-		return nil, nil, fmt.Errorf("could not list namespaces: %w", err)
+		return nil, err
 	}
+	return workloads, nil
+}
 
-	var deployments []appsv1.Deployment
-	var nsNames []string
-	for _, ns := range namespaces.Items {
-		if strings.HasPrefix(ns.Name, "prd-") || strings.HasPrefix(ns.Name, "product-") {
-			deps, err := clientset.AppsV1().Deployments(ns.Name).List(context.Background(), metav1.ListOptions{})
-			if err != nil {
-				fmt.Printf("could not list deployments in namespace %s: %v\n", ns.Name, err)
-				continue
-			}
-			for _, dep := range deps.Items {
-				deployments = append(deployments, dep)
-				nsNames = append(nsNames, ns.Name)
-			}
-		}
+// EachScopedWorkload 는 scope 에 맞는 namespace 의 워크로드마다 fn 을 호출한다
+func EachScopedWorkload(ctx context.Context, clientset kubernetes.Interface, scope NamespaceScope, fn func(*Workload) error) error {
+	err := EachScopedNamespace(ctx, clientset, scope, func(namespace string) error {
+		return EachWorkload(ctx, clientset, namespace, fn)
+	})
+	if err != nil {
+		return fmt.Errorf("could not list workloads: %w", err)
 	}
-	return deployments, nsNames, nil
+	return nil
 }
 
-func extractResources(dep appsv1.Deployment) (cpuRequest, memLimit string) {
-	containers := dep.Spec.Template.Spec.Containers
-	if len(containers) > 0 {
-		// Excluding sidecars like filebeat and nginx
-		for _, container := range containers {
-			if container.Name == "filebeat" || container.Name == "nginx" {
-				continue
-			}
-			resources := container.Resources
-			if req, ok := resources.Requests[corev1.ResourceCPU]; ok {
-				cpuRequest = req.String()
-			}
-			if lim, ok := resources.Limits[corev1.ResourceMemory]; ok {
-				memLimit = lim.String()
-			}
-			// Only the first main container is considered
-			break
-		}
-	}
-	return
+// ReportOptions 는 리포트 대상 namespace 와 제외할 사이드카 컨테이너 이름 등을 지정
+type ReportOptions struct {
+	Scope              NamespaceScope
+	ExcludedContainers []string
 }
 
-func GetPodMetadata(clientset *kubernetes.Clientset) error {
-	deployments, nsNames, err := listDeployments(clientset)
+// OptionsFromEnv 는 namespace scope 설정과 REPORT_EXCLUDED_CONTAINERS(쉼표 구분) 환경 변수로 기본 옵션을 만든다
+func OptionsFromEnv() (ReportOptions, error) {
+	scope, err := ScopeFromEnv()
 	if err != nil {
-		fmt.Printf("Error listing deployments: %v\n", err)
-		return err
+		return ReportOptions{}, err
 	}
+	return ReportOptions{
+		Scope:              scope,
+		ExcludedContainers: SplitList(os.Getenv("REPORT_EXCLUDED_CONTAINERS")),
+	}, nil
+}
 
-	file, err := os.Create("deployment_resources.csv")
-	if err != nil {
-		fmt.Printf("Error creating file: %v\n", err)
-		return err
+// SplitList 는 쉼표로 구분된 문자열을 공백을 제거한 목록으로 변환
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
-	defer file.Close()
+	return items
+}
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+type workloadResourceType struct {
+	Namespace    string
+	WorkloadKind string
+	WorkloadName string
+	Replicas     int32
+	QOSClass     corev1.PodQOSClass
+	PodTotals    resourceValuesType
+	Containers   []containerResourceType
+}
 
-	if err := writer.Write([]string{"Namespace", "Deployment Name", "CPU Request", "Memory Limit"}); err != nil {
-		fmt.Printf("Error writing header: %v\n", err)
-		return err
+// ResourceReport 는 워크로드별 리소스 정보를 CSV/JSON 으로 내보낼 수 있도록 report.Table 을 구현
+type ResourceReport struct {
+	Workloads []workloadResourceType
+}
+
+func (r ResourceReport) Header() []string {
+	return []string{
+		"Namespace", "Workload Kind", "Workload Name", "Replicas", "QoS Class", "Container", "Init Container", "Image",
+		"CPU Request", "CPU Limit", "Memory Request", "Memory Limit",
+		"Ephemeral Storage Request", "Ephemeral Storage Limit",
+		"Pod CPU Request", "Pod CPU Limit", "Pod Memory Request", "Pod Memory Limit",
+		"Pod Ephemeral Storage Request", "Pod Ephemeral Storage Limit",
 	}
+}
 
-	for i, dep := range deployments {
-		cpuRequest, memLimit := extractResources(dep)
-		if cpuRequest != "" && memLimit != "" {
-			if err := writer.Write([]string{nsNames[i], dep.Name, cpuRequest, memLimit}); err != nil {
-				fmt.Printf("Error writing to CSV: %v\n", err)
-				continue
-			}
+// Rows 는 컨테이너마다 한 행을 만들고 파드 합계는 각 행에 반복해서 기록
+func (r ResourceReport) Rows() [][]string {
+	var rows [][]string
+	for _, w := range r.Workloads {
+		for _, container := range w.Containers {
+			row := []string{w.Namespace, w.WorkloadKind, w.WorkloadName, strconv.Itoa(int(w.Replicas)), string(w.QOSClass), container.Name, strconv.FormatBool(container.Init), container.Image}
+			row = append(row, container.Resources.columns()...)
+			row = append(row, w.PodTotals.columns()...)
+			rows = append(rows, row)
 		}
 	}
-	fmt.Println("CSV generation complete!")
+	return rows
+}
 
-	return nil
+func (r ResourceReport) Records() []interface{} {
+	records := make([]interface{}, 0, len(r.Workloads))
+	for _, w := range r.Workloads {
+		records = append(records, w)
+	}
+	return records
+}
+
+func (v resourceValuesType) columns() []string {
+	return []string{
+		v.CPURequest, v.CPULimit, v.MemoryRequest, v.MemoryLimit,
+		v.EphemeralStorageRequest, v.EphemeralStorageLimit,
+	}
+}
+
+func GetPodMetadata(clientset kubernetes.Interface, opts ReportOptions) (ResourceReport, error) {
+	excluded := make(map[string]bool, len(opts.ExcludedContainers))
+	for _, name := range opts.ExcludedContainers {
+		excluded[name] = true
+	}
+
+	// pod template 전체를 모아두지 않고 받는 대로 리포트 행으로 줄인다.
+	// QoS 와 파드 합계는 실제 스케줄링 기준이므로 제외한 사이드카까지 포함해서 계산
+	var resourceReport ResourceReport
+	err := EachScopedWorkload(context.Background(), clientset, opts.Scope, func(w *Workload) error {
+		spec := w.Template.Spec
+		resourceReport.Workloads = append(resourceReport.Workloads, workloadResourceType{
+			Namespace:    w.Namespace,
+			WorkloadKind: w.Kind,
+			WorkloadName: w.Name,
+			Replicas:     w.Replicas,
+			QOSClass:     qosClass(spec),
+			PodTotals:    podTotals(spec),
+			Containers:   extractContainers(spec, excluded),
+		})
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error listing workloads: %v\n", err)
+		return ResourceReport{}, err
+	}
+
+	return resourceReport, nil
 }
@@ -0,0 +1,175 @@
+package pod_metadata
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var trackedResources = []corev1.ResourceName{
+	corev1.ResourceCPU,
+	corev1.ResourceMemory,
+	corev1.ResourceEphemeralStorage,
+}
+
+type resourceValuesType struct {
+	CPURequest              string
+	CPULimit                string
+	MemoryRequest           string
+	MemoryLimit             string
+	EphemeralStorageRequest string
+	EphemeralStorageLimit   string
+}
+
+type containerResourceType struct {
+	Name      string
+	Image     string
+	Init      bool
+	Resources resourceValuesType
+}
+
+func newResourceValues(requests, limits corev1.ResourceList) resourceValuesType {
+	return resourceValuesType{
+		CPURequest:              quantityString(requests, corev1.ResourceCPU),
+		CPULimit:                quantityString(limits, corev1.ResourceCPU),
+		MemoryRequest:           quantityString(requests, corev1.ResourceMemory),
+		MemoryLimit:             quantityString(limits, corev1.ResourceMemory),
+		EphemeralStorageRequest: quantityString(requests, corev1.ResourceEphemeralStorage),
+		EphemeralStorageLimit:   quantityString(limits, corev1.ResourceEphemeralStorage),
+	}
+}
+
+func quantityString(list corev1.ResourceList, name corev1.ResourceName) string {
+	if q, ok := list[name]; ok {
+		return q.String()
+	}
+	return ""
+}
+
+// extractContainers 는 init 컨테이너를 포함한 모든 컨테이너의 리소스를 반환한다.
+// excluded 에 포함된 이름(사이드카 등)은 목록에서 제외
+func extractContainers(spec corev1.PodSpec, excluded map[string]bool) []containerResourceType {
+	var containers []containerResourceType
+	appendContainers := func(list []corev1.Container, init bool) {
+		for _, container := range list {
+			if excluded[container.Name] {
+				continue
+			}
+			containers = append(containers, containerResourceType{
+				Name:      container.Name,
+				Image:     container.Image,
+				Init:      init,
+				Resources: newResourceValues(container.Resources.Requests, container.Resources.Limits),
+			})
+		}
+	}
+	appendContainers(spec.InitContainers, true)
+	appendContainers(spec.Containers, false)
+	return containers
+}
+
+// podTotals 는 스케줄러와 같은 방식으로 파드 하나의 유효 requests/limits 를 계산한다.
+// 일반 컨테이너의 합과 init 컨테이너 중 최댓값 중 큰 값에 pod overhead 를 더한다.
+// limit 이 없는 컨테이너가 하나라도 있으면 해당 리소스의 limit 합계는 비워둔다.
+func podTotals(spec corev1.PodSpec) resourceValuesType {
+	return newResourceValues(EffectiveRequests(spec), effectiveLimits(spec))
+}
+
+// EffectiveRequests 는 파드 하나가 스케줄링 시 차지하는 requests 를 반환
+func EffectiveRequests(spec corev1.PodSpec) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for _, name := range trackedResources {
+		if total, ok := effectiveTotal(spec, name, func(c corev1.Container) corev1.ResourceList { return c.Resources.Requests }, false); ok {
+			requests[name] = total
+		}
+	}
+	return requests
+}
+
+func effectiveLimits(spec corev1.PodSpec) corev1.ResourceList {
+	limits := corev1.ResourceList{}
+	for _, name := range trackedResources {
+		if total, ok := effectiveTotal(spec, name, func(c corev1.Container) corev1.ResourceList { return c.Resources.Limits }, true); ok {
+			limits[name] = total
+		}
+	}
+	return limits
+}
+
+func effectiveTotal(spec corev1.PodSpec, name corev1.ResourceName, list func(corev1.Container) corev1.ResourceList, requireAll bool) (resource.Quantity, bool) {
+	sum := resource.Quantity{}
+	found := false
+	for _, container := range spec.Containers {
+		q, ok := list(container)[name]
+		if !ok {
+			if requireAll {
+				return resource.Quantity{}, false
+			}
+			continue
+		}
+		sum.Add(q)
+		found = true
+	}
+
+	for _, container := range spec.InitContainers {
+		q, ok := list(container)[name]
+		if !ok {
+			continue
+		}
+		if q.Cmp(sum) > 0 {
+			sum = q.DeepCopy()
+		}
+		found = true
+	}
+
+	if overhead, ok := spec.Overhead[name]; ok && found {
+		sum.Add(overhead)
+	}
+	return sum, found
+}
+
+// qosClass 는 kubelet 의 QoS 분류 규칙을 따른다
+func qosClass(spec corev1.PodSpec) corev1.PodQOSClass {
+	allContainers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+
+	hasAny := false
+	guaranteed := true
+	for _, container := range allContainers {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			request, hasRequest := container.Resources.Requests[name]
+			limit, hasLimit := container.Resources.Limits[name]
+			if hasRequest && !request.IsZero() || hasLimit && !limit.IsZero() {
+				hasAny = true
+			}
+			if !hasLimit {
+				guaranteed = false
+				continue
+			}
+			// request 가 없으면 limit 과 같은 값으로 기본 설정된다
+			if hasRequest && request.Cmp(limit) != 0 {
+				guaranteed = false
+			}
+		}
+	}
+
+	switch {
+	case !hasAny:
+		return corev1.PodQOSBestEffort
+	case guaranteed:
+		return corev1.PodQOSGuaranteed
+	default:
+		return corev1.PodQOSBurstable
+	}
+}
+
+// Fields 는 스냅샷 비교에 사용할 컨테이너 속성을 (이름, 값) 목록으로 반환
+func (c containerResourceType) Fields() [][2]string {
+	return [][2]string{
+		{"Image", c.Image},
+		{"CPURequest", c.Resources.CPURequest},
+		{"CPULimit", c.Resources.CPULimit},
+		{"MemoryRequest", c.Resources.MemoryRequest},
+		{"MemoryLimit", c.Resources.MemoryLimit},
+		{"EphemeralStorageRequest", c.Resources.EphemeralStorageRequest},
+		{"EphemeralStorageLimit", c.Resources.EphemeralStorageLimit},
+	}
+}
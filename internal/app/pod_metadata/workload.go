@@ -0,0 +1,128 @@
+package pod_metadata
+
+import (
+	"client-go/internal/pkg/kube"
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	KindDeployment            = "Deployment"
+	KindStatefulSet           = "StatefulSet"
+	KindDaemonSet             = "DaemonSet"
+	KindReplicaSet            = "ReplicaSet"
+	KindReplicationController = "ReplicationController"
+	KindJob                   = "Job"
+	KindCronJob               = "CronJob"
+)
+
+// Workload 는 pod template 을 가진 모든 워크로드를 같은 형태로 다루기 위한 타입
+type Workload struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Labels    map[string]string
+	Replicas  int32
+	Template  corev1.PodTemplateSpec
+}
+
+// ListWorkloads 는 namespace 안의 pod template 워크로드를 모두 가져온다
+func ListWorkloads(ctx context.Context, clientset kubernetes.Interface, namespace string) ([]Workload, error) {
+	var workloads []Workload
+	err := EachWorkload(ctx, clientset, namespace, func(w *Workload) error {
+		workloads = append(workloads, *w)
+		return nil
+	})
+	return workloads, err
+}
+
+// EachWorkload 는 namespace 안의 pod template 워크로드마다 fn 을 호출한다. 목록을 페이지 단위로 처리하므로 전체를 메모리에 올리지 않는다.
+// 다른 워크로드가 관리하는 ReplicaSet(Deployment 소유) 과 Job(CronJob 소유) 은 중복이므로 제외
+func EachWorkload(ctx context.Context, clientset kubernetes.Interface, namespace string, fn func(*Workload) error) error {
+	add := func(kind string, meta metav1.ObjectMeta, replicas int32, template corev1.PodTemplateSpec) error {
+		return fn(&Workload{
+			Kind:      kind,
+			Namespace: meta.Namespace,
+			Name:      meta.Name,
+			Labels:    meta.Labels,
+			Replicas:  replicas,
+			Template:  template,
+		})
+	}
+
+	listers := []struct {
+		kind string
+		list func() error
+	}{
+		{KindDeployment, func() error {
+			return kube.EachDeployment(ctx, clientset, namespace, metav1.ListOptions{}, func(dep *appsv1.Deployment) error {
+				return add(KindDeployment, dep.ObjectMeta, replicasOrDefault(dep.Spec.Replicas), dep.Spec.Template)
+			})
+		}},
+		{KindStatefulSet, func() error {
+			return kube.EachStatefulSet(ctx, clientset, namespace, metav1.ListOptions{}, func(sts *appsv1.StatefulSet) error {
+				return add(KindStatefulSet, sts.ObjectMeta, replicasOrDefault(sts.Spec.Replicas), sts.Spec.Template)
+			})
+		}},
+		{KindDaemonSet, func() error {
+			return kube.EachDaemonSet(ctx, clientset, namespace, metav1.ListOptions{}, func(ds *appsv1.DaemonSet) error {
+				// DaemonSet 은 replicas 가 없으므로 스케줄돼야 하는 노드 수를 사용
+				return add(KindDaemonSet, ds.ObjectMeta, ds.Status.DesiredNumberScheduled, ds.Spec.Template)
+			})
+		}},
+		{KindReplicaSet, func() error {
+			return kube.EachReplicaSet(ctx, clientset, namespace, metav1.ListOptions{}, func(rs *appsv1.ReplicaSet) error {
+				if hasController(rs.ObjectMeta) {
+					return nil
+				}
+				return add(KindReplicaSet, rs.ObjectMeta, replicasOrDefault(rs.Spec.Replicas), rs.Spec.Template)
+			})
+		}},
+		{KindReplicationController, func() error {
+			return kube.EachReplicationController(ctx, clientset, namespace, metav1.ListOptions{}, func(rc *corev1.ReplicationController) error {
+				if rc.Spec.Template == nil {
+					return nil
+				}
+				return add(KindReplicationController, rc.ObjectMeta, replicasOrDefault(rc.Spec.Replicas), *rc.Spec.Template)
+			})
+		}},
+		{KindJob, func() error {
+			return kube.EachJob(ctx, clientset, namespace, metav1.ListOptions{}, func(job *batchv1.Job) error {
+				if hasController(job.ObjectMeta) {
+					return nil
+				}
+				return add(KindJob, job.ObjectMeta, replicasOrDefault(job.Spec.Parallelism), job.Spec.Template)
+			})
+		}},
+		{KindCronJob, func() error {
+			return kube.EachCronJob(ctx, clientset, namespace, metav1.ListOptions{}, func(cj *batchv1.CronJob) error {
+				return add(KindCronJob, cj.ObjectMeta, replicasOrDefault(cj.Spec.JobTemplate.Spec.Parallelism), cj.Spec.JobTemplate.Spec.Template)
+			})
+		}},
+	}
+
+	// 일부 kind 를 빠뜨린 리포트가 정상 응답으로 나가지 않도록 목록 오류는 그대로 반환한다
+	for _, lister := range listers {
+		if err := lister.list(); err != nil {
+			return fmt.Errorf("could not list %ss in namespace %s: %w", lister.kind, namespace, err)
+		}
+	}
+	return ctx.Err()
+}
+
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+func hasController(meta metav1.ObjectMeta) bool {
+	return metav1.GetControllerOf(&meta) != nil
+}
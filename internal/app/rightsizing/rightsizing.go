@@ -0,0 +1,393 @@
+package rightsizing
+
+import (
+	"client-go/config"
+	"client-go/internal/app/pod_metadata"
+	"client-go/internal/pkg/kube"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/api"
+	"github.com/prometheus/common/model"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	defaultWindow   = "7d"
+	defaultHeadroom = 20
+
+	// %s 에는 scope 의 namespace matcher 가 들어간다
+	cpuUsageExpr    = `sum by (namespace, pod, container) (rate(container_cpu_usage_seconds_total{container!="", container!="POD"%s}[5m]))`
+	memoryUsageExpr = `sum by (namespace, pod, container) (container_memory_working_set_bytes{container!="", container!="POD"%s})`
+
+	minCPUMillis   = 10
+	minMemoryBytes = 16 * 1024 * 1024
+)
+
+// Options 는 사용량을 볼 기간(Prometheus duration)과 권장값에 더할 여유분(%)을 지정
+type Options struct {
+	Scope    pod_metadata.NamespaceScope
+	Window   string
+	Headroom float64
+}
+
+type usageType struct {
+	P50 float64
+	P95 float64
+	Max float64
+}
+
+type resourceSizingType struct {
+	Request            string
+	Limit              string
+	Usage              usageType
+	WastePercentage    float64
+	RecommendedRequest string
+	RecommendedLimit   string
+}
+
+type containerSizingType struct {
+	Namespace    string
+	WorkloadKind string
+	WorkloadName string
+	Container    string
+	CPU          resourceSizingType
+	Memory       resourceSizingType
+}
+
+// Report 는 컨테이너별 request/limit 과 실제 사용량을 합친 결과로 report.Table 을 구현
+type Report struct {
+	Window     string
+	Containers []containerSizingType
+}
+
+// OptionsFromEnv 는 RIGHTSIZING_WINDOW, RIGHTSIZING_HEADROOM_PERCENT 환경 변수로 기본 옵션을 만든다
+func OptionsFromEnv() Options {
+	opts := Options{Window: defaultWindow, Headroom: defaultHeadroom}
+	if window := os.Getenv("RIGHTSIZING_WINDOW"); window != "" {
+		opts.Window = window
+	}
+	if headroom, err := strconv.ParseFloat(os.Getenv("RIGHTSIZING_HEADROOM_PERCENT"), 64); err == nil && headroom >= 0 {
+		opts.Headroom = headroom
+	}
+	return opts
+}
+
+// Validate 는 Window 가 0 보다 긴 Prometheus duration 이고 Headroom 이 음수가 아닌지 확인한다
+func (o Options) Validate() error {
+	window, err := model.ParseDuration(o.Window)
+	if err != nil {
+		return fmt.Errorf("invalid window %q: %w", o.Window, err)
+	}
+	if window <= 0 {
+		return fmt.Errorf("invalid window %q: must be positive", o.Window)
+	}
+	if o.Headroom < 0 {
+		return fmt.Errorf("invalid headroom %v: must not be negative", o.Headroom)
+	}
+	return nil
+}
+
+func GetRightsizingReport(clientSet kubernetes.Interface, opts Options) (Report, error) {
+	if err := opts.Validate(); err != nil {
+		return Report{}, err
+	}
+
+	var namespaces []string
+	err := pod_metadata.EachScopedNamespace(context.Background(), clientSet, opts.Scope, func(namespace string) error {
+		namespaces = append(namespaces, namespace)
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to list namespaces")
+		return Report{}, err
+	}
+	sizingReport := Report{Window: opts.Window}
+	if len(namespaces) == 0 {
+		return sizingReport, nil
+	}
+
+	var workloads []pod_metadata.Workload
+	resolver := newWorkloadResolver()
+	for _, namespace := range namespaces {
+		err := pod_metadata.EachWorkload(context.Background(), clientSet, namespace, func(w *pod_metadata.Workload) error {
+			workloads = append(workloads, *w)
+			return nil
+		})
+		if err == nil {
+			err = resolver.load(context.Background(), clientSet, namespace)
+		}
+		if err != nil {
+			log.WithError(err).Error("Failed to list workloads")
+			return Report{}, err
+		}
+	}
+
+	prometheusClient, err := config.CreatePrometheusClient()
+	if err != nil {
+		log.WithError(err).Error("Failed to create Prometheus client")
+		return Report{}, err
+	}
+
+	matcher := namespaceMatcher(opts.Scope, namespaces)
+	cpuUsage, err := queryUsage(prometheusClient, fmt.Sprintf(cpuUsageExpr, matcher), opts.Window)
+	if err != nil {
+		return Report{}, err
+	}
+	memoryUsage, err := queryUsage(prometheusClient, fmt.Sprintf(memoryUsageExpr, matcher), opts.Window)
+	if err != nil {
+		return Report{}, err
+	}
+
+	cpuByContainer := resolver.aggregate(cpuUsage)
+	memoryByContainer := resolver.aggregate(memoryUsage)
+
+	for _, w := range workloads {
+		for _, container := range w.Template.Spec.Containers {
+			key := containerKey{namespace: w.Namespace, kind: w.Kind, workload: w.Name, container: container.Name}
+			sizingReport.Containers = append(sizingReport.Containers, containerSizingType{
+				Namespace:    w.Namespace,
+				WorkloadKind: w.Kind,
+				WorkloadName: w.Name,
+				Container:    container.Name,
+				CPU:          sizeCPU(container.Resources, cpuByContainer[key], opts.Headroom),
+				Memory:       sizeMemory(container.Resources, memoryByContainer[key], opts.Headroom),
+			})
+		}
+	}
+	return sizingReport, nil
+}
+
+// namespaceMatcher 는 scope 에 맞는 namespace 만 조회하도록 PromQL label matcher 를 만든다. scope 가 비어 있으면 모든 namespace 를 조회한다
+func namespaceMatcher(scope pod_metadata.NamespaceScope, namespaces []string) string {
+	if scope.IsEmpty() {
+		return ""
+	}
+	quoted := make([]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		quoted = append(quoted, regexp.QuoteMeta(namespace))
+	}
+	// backtick 문자열은 PromQL 에서 escape 를 해석하지 않는다
+	return ", namespace=~`" + strings.Join(quoted, "|") + "`"
+}
+
+// queryUsage 는 window 동안의 p50/p95/max 를 (namespace, pod, container) 단위로 조회
+func queryUsage(prometheusClient api.Client, expr, window string) (map[podContainerKey]usageType, error) {
+	usage := map[podContainerKey]usageType{}
+	queries := []struct {
+		query string
+		set   func(u *usageType, v float64)
+	}{
+		{fmt.Sprintf("quantile_over_time(0.5, (%s)[%s:5m])", expr, window), func(u *usageType, v float64) { u.P50 = v }},
+		{fmt.Sprintf("quantile_over_time(0.95, (%s)[%s:5m])", expr, window), func(u *usageType, v float64) { u.P95 = v }},
+		{fmt.Sprintf("max_over_time((%s)[%s:5m])", expr, window), func(u *usageType, v float64) { u.Max = v }},
+	}
+	for _, q := range queries {
+		result, err := config.QueryPrometheus(prometheusClient, q.query)
+		if err != nil {
+			log.WithError(err).Error("Failed to query Prometheus")
+			return nil, err
+		}
+		for _, sample := range result {
+			key := podContainerKey{
+				namespace: string(sample.Metric["namespace"]),
+				pod:       string(sample.Metric["pod"]),
+				container: string(sample.Metric["container"]),
+			}
+			u := usage[key]
+			q.set(&u, float64(sample.Value))
+			usage[key] = u
+		}
+	}
+	return usage, nil
+}
+
+type podContainerKey struct {
+	namespace string
+	pod       string
+	container string
+}
+
+type containerKey struct {
+	namespace string
+	kind      string
+	workload  string
+	container string
+}
+
+// workloadResolver 는 파드 이름으로 워크로드를 찾는다.
+// 살아 있는 파드는 owner reference 로 찾고, window 안에 사라진 Deployment 파드는
+// 남아 있는 ReplicaSet 이름("<ReplicaSet 이름>-<임의 문자열>")으로 찾는다.
+type workloadResolver struct {
+	pods        map[string]workloadRefType
+	replicaSets map[string]workloadRefType
+}
+
+type workloadRefType struct {
+	kind string
+	name string
+}
+
+func newWorkloadResolver() *workloadResolver {
+	return &workloadResolver{pods: map[string]workloadRefType{}, replicaSets: map[string]workloadRefType{}}
+}
+
+// load 는 namespace 의 파드와 Deployment 가 만든 ReplicaSet 의 소유 워크로드를 기록한다
+func (r *workloadResolver) load(ctx context.Context, clientSet kubernetes.Interface, namespace string) error {
+	err := kube.EachPod(ctx, clientSet, namespace, metav1.ListOptions{}, func(pod *corev1.Pod) error {
+		kind, name := kube.OwnerWorkload(pod)
+		r.pods[namespace+"/"+pod.Name] = workloadRefType{kind: kind, name: name}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not list pods in namespace %s: %w", namespace, err)
+	}
+	err = kube.EachReplicaSet(ctx, clientSet, namespace, metav1.ListOptions{}, func(rs *appsv1.ReplicaSet) error {
+		if owner := metav1.GetControllerOf(rs); owner != nil && owner.Kind == "Deployment" {
+			r.replicaSets[namespace+"/"+rs.Name] = workloadRefType{kind: owner.Kind, name: owner.Name}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not list replicasets in namespace %s: %w", namespace, err)
+	}
+	return nil
+}
+
+func (r *workloadResolver) resolve(namespace, pod string) (workloadRefType, bool) {
+	if ref, ok := r.pods[namespace+"/"+pod]; ok {
+		return ref, true
+	}
+	if i := strings.LastIndex(pod, "-"); i > 0 {
+		if ref, ok := r.replicaSets[namespace+"/"+pod[:i]]; ok {
+			return ref, true
+		}
+	}
+	return workloadRefType{}, false
+}
+
+// aggregate 는 같은 워크로드 컨테이너의 파드별 사용량 중 가장 큰 값을 사용한다(보수적으로 추정)
+func (r *workloadResolver) aggregate(usage map[podContainerKey]usageType) map[containerKey]usageType {
+	result := map[containerKey]usageType{}
+	for key, u := range usage {
+		w, ok := r.resolve(key.namespace, key.pod)
+		if !ok {
+			continue
+		}
+		ck := containerKey{namespace: key.namespace, kind: w.kind, workload: w.name, container: key.container}
+		agg := result[ck]
+		agg.P50 = math.Max(agg.P50, u.P50)
+		agg.P95 = math.Max(agg.P95, u.P95)
+		agg.Max = math.Max(agg.Max, u.Max)
+		result[ck] = agg
+	}
+	return result
+}
+
+func sizeCPU(resources corev1.ResourceRequirements, usage usageType, headroom float64) resourceSizingType {
+	sizing := newResourceSizing(resources, corev1.ResourceCPU, usage)
+	// 사용량 데이터가 없으면 권장값을 만들지 않는다
+	if usage.Max > 0 {
+		factor := 1 + headroom/100
+		sizing.RecommendedRequest = cpuQuantity(usage.P95 * factor)
+		sizing.RecommendedLimit = cpuQuantity(usage.Max * factor)
+	}
+	return sizing
+}
+
+func sizeMemory(resources corev1.ResourceRequirements, usage usageType, headroom float64) resourceSizingType {
+	sizing := newResourceSizing(resources, corev1.ResourceMemory, usage)
+	// 사용량 데이터가 없으면 권장값을 만들지 않는다
+	if usage.Max > 0 {
+		factor := 1 + headroom/100
+		sizing.RecommendedRequest = memoryQuantity(usage.P95 * factor)
+		sizing.RecommendedLimit = memoryQuantity(usage.Max * factor)
+	}
+	return sizing
+}
+
+// newResourceSizing 은 request 대비 p95 사용량을 제외한 나머지를 낭비 비율로 계산
+func newResourceSizing(resources corev1.ResourceRequirements, name corev1.ResourceName, usage usageType) resourceSizingType {
+	sizing := resourceSizingType{Usage: usage}
+	if request, ok := resources.Requests[name]; ok {
+		sizing.Request = request.String()
+		if requested := request.AsApproximateFloat64(); requested > 0 && usage.P95 < requested {
+			sizing.WastePercentage = math.Round((requested-usage.P95)/requested*10000) / 100
+		}
+	}
+	if limit, ok := resources.Limits[name]; ok {
+		sizing.Limit = limit.String()
+	}
+	return sizing
+}
+
+func cpuQuantity(cores float64) string {
+	millis := int64(math.Ceil(cores * 1000))
+	if millis < minCPUMillis {
+		millis = minCPUMillis
+	}
+	return resource.NewMilliQuantity(millis, resource.DecimalSI).String()
+}
+
+func memoryQuantity(bytes float64) string {
+	const mebibyte = 1024 * 1024
+	rounded := int64(math.Ceil(bytes/mebibyte)) * mebibyte
+	if rounded < minMemoryBytes {
+		rounded = minMemoryBytes
+	}
+	return resource.NewQuantity(rounded, resource.BinarySI).String()
+}
+
+func (r Report) Header() []string {
+	return []string{
+		"Namespace", "Workload Kind", "Workload Name", "Container",
+		"CPU Request", "CPU Limit", "CPU P50", "CPU P95", "CPU Max", "CPU Waste %", "Recommended CPU Request", "Recommended CPU Limit",
+		"Memory Request", "Memory Limit", "Memory P50", "Memory P95", "Memory Max", "Memory Waste %", "Recommended Memory Request", "Recommended Memory Limit",
+	}
+}
+
+func (r Report) Rows() [][]string {
+	rows := make([][]string, 0, len(r.Containers))
+	for _, c := range r.Containers {
+		row := []string{c.Namespace, c.WorkloadKind, c.WorkloadName, c.Container}
+		row = append(row, c.CPU.columns(formatCores)...)
+		row = append(row, c.Memory.columns(formatBytes)...)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (r Report) Records() []interface{} {
+	records := make([]interface{}, 0, len(r.Containers))
+	for _, c := range r.Containers {
+		records = append(records, c)
+	}
+	return records
+}
+
+func (s resourceSizingType) columns(format func(float64) string) []string {
+	return []string{
+		s.Request, s.Limit,
+		format(s.Usage.P50), format(s.Usage.P95), format(s.Usage.Max),
+		strconv.FormatFloat(s.WastePercentage, 'f', 2, 64),
+		s.RecommendedRequest, s.RecommendedLimit,
+	}
+}
+
+func formatCores(cores float64) string {
+	return strconv.FormatFloat(cores, 'f', 3, 64)
+}
+
+func formatBytes(bytes float64) string {
+	return resource.NewQuantity(int64(bytes), resource.BinarySI).String()
+}
@@ -0,0 +1,197 @@
+package slack_command
+
+import (
+	evictedpod "client-go/internal/app/evicted_pod"
+	"client-go/internal/app/node"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxListedItems = 30
+
+// commandType 은 chat 에서 실행할 수 있는 명령 하나
+type commandType struct {
+	Name  string
+	Usage string
+	// Description 은 도움말과 확인 메시지에 쓰인다 ("<명령> will <Description>")
+	Description string
+	// Destructive 면 확인 버튼을 누른 뒤에만 실행한다
+	Destructive bool
+	// MaxArgs 를 넘는 인자는 받지 않는다. 인자는 모두 메모리 사용률(%) 이다
+	MaxArgs int
+	run     func(h *Handler, args []string) (string, error)
+}
+
+var commands = []commandType{
+	{
+		Name:        "drain-dry-run",
+		Usage:       "drain-dry-run [percentage=70]",
+		Description: "list nodes under the memory usage that would be drained",
+		MaxArgs:     1,
+		run:         (*Handler).drainDryRun,
+	},
+	{
+		Name:        "drain",
+		Usage:       "drain [percentage=70]",
+		Description: "cordon and drain nodes under the memory usage",
+		Destructive: true,
+		MaxArgs:     1,
+		run:         (*Handler).drain,
+	},
+	{
+		Name:        "evicted-pods",
+		Usage:       "evicted-pods",
+		Description: "preview evicted pods that would be deleted",
+		run:         (*Handler).evictedPodsPreview,
+	},
+	{
+		Name:        "evicted-pods-clean",
+		Usage:       "evicted-pods-clean",
+		Description: "delete all evicted pods outside protected namespaces",
+		Destructive: true,
+		run:         (*Handler).evictedPodsClean,
+	},
+	{
+		Name:        "node-memory",
+		Usage:       "node-memory [percentage=20]",
+		Description: "report nodes under the memory usage",
+		MaxArgs:     1,
+		run:         (*Handler).nodeMemory,
+	},
+}
+
+func findCommand(name string) (commandType, bool) {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd, true
+		}
+	}
+	return commandType{}, false
+}
+
+func helpText(slashCommand string) string {
+	if slashCommand == "" {
+		slashCommand = "/k8s"
+	}
+	lines := []string{"*Available commands:*"}
+	for _, cmd := range commands {
+		line := fmt.Sprintf("- `%s %s`: %s", slashCommand, cmd.Usage, cmd.Description)
+		if cmd.Destructive {
+			line += " (requires confirmation)"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validate 는 인자 개수와 값을 검사한다. 사용률은 Prometheus 쿼리에 그대로 들어가므로 숫자만 받는다
+func (c commandType) validate(args []string) error {
+	if len(args) > c.MaxArgs {
+		return fmt.Errorf("too many arguments for %s", c.Name)
+	}
+	for _, arg := range args {
+		if _, err := parsePercentage(arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parsePercentage(value string) (string, error) {
+	percentage, err := strconv.ParseFloat(value, 64)
+	if err != nil || percentage <= 0 || percentage > 100 {
+		return "", fmt.Errorf("invalid percentage %q: use a number between 0 and 100", value)
+	}
+	return strconv.FormatFloat(percentage, 'f', -1, 64), nil
+}
+
+func percentageArg(args []string, defaultValue string) string {
+	if len(args) == 0 {
+		return defaultValue
+	}
+	percentage, _ := parsePercentage(args[0])
+	return percentage
+}
+
+func (h *Handler) drainDryRun(args []string) (string, error) {
+	percentage := percentageArg(args, "70")
+	results, err := node.NodeDrain(h.clientSet, percentage, "true")
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return fmt.Sprintf("*Drain dry run:* no nodes under `%s%%` memory usage would be drained", percentage), nil
+	}
+
+	lines := make([]string, 0, len(results))
+	for _, result := range results {
+		lines = append(lines, fmt.Sprintf("%-30s %-15s %-15s %5.1f%%", result.NodeName, result.InstanceType, result.ProvisionerName, result.Percentage))
+	}
+	return fmt.Sprintf("*Drain dry run:* %d nodes under `%s%%` memory usage would be drained\n%s",
+		len(results), percentage, codeBlock(lines)), nil
+}
+
+func (h *Handler) drain(args []string) (string, error) {
+	percentage := percentageArg(args, "70")
+	if _, err := node.NodeDrain(h.clientSet, percentage, "false"); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(":white_check_mark: Drain of nodes under `%s%%` memory usage completed", percentage), nil
+}
+
+func (h *Handler) evictedPodsPreview(args []string) (string, error) {
+	summary, err := evictedpod.PreviewEvictedPods(h.clientSet)
+	if err != nil {
+		return "", err
+	}
+	if summary.Total == 0 {
+		return "*Evicted pods:* none found", nil
+	}
+
+	lines := make([]string, 0, len(summary.Pods))
+	for _, pod := range summary.Pods {
+		lines = append(lines, pod.Namespace+"/"+pod.PodName)
+	}
+	sort.Strings(lines)
+	return fmt.Sprintf("*Evicted pods:* %d would be deleted\n%s", summary.Total, codeBlock(lines)), nil
+}
+
+func (h *Handler) evictedPodsClean(args []string) (string, error) {
+	summary, err := evictedpod.EvictedPods(h.clientSet, evictedpod.OptionsFromEnv())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(":white_check_mark: Deleted `%d`, already gone `%d`, failed `%d` of `%d` evicted pods",
+		summary.Deleted, summary.AlreadyGone, summary.Failed, summary.Total), nil
+}
+
+func (h *Handler) nodeMemory(args []string) (string, error) {
+	percentage := percentageArg(args, "20")
+	usages, err := node.GetNodeMemoryUsage(h.clientSet, percentage)
+	if err != nil {
+		return "", err
+	}
+	if len(usages) == 0 {
+		return fmt.Sprintf("*Node memory:* no nodes under `%s%%` memory usage", percentage), nil
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].MemoryUsage < usages[j].MemoryUsage
+	})
+	lines := make([]string, 0, len(usages))
+	for _, usage := range usages {
+		lines = append(lines, fmt.Sprintf("%-20s %5.1f%%", usage.NodeName, usage.MemoryUsage))
+	}
+	return fmt.Sprintf("*Node memory:* %d nodes under `%s%%` memory usage\n%s", len(usages), percentage, codeBlock(lines)), nil
+}
+
+// codeBlock 은 목록을 코드 블록으로 감싼다. 메시지가 너무 길어지지 않도록 maxListedItems 개까지만 보여준다
+func codeBlock(lines []string) string {
+	if len(lines) > maxListedItems {
+		hidden := len(lines) - maxListedItems
+		lines = append(lines[:maxListedItems:maxListedItems], fmt.Sprintf("... and %d more", hidden))
+	}
+	return "```\n" + strings.Join(lines, "\n") + "\n```"
+}
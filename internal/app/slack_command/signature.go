@@ -0,0 +1,47 @@
+package slack_command
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+)
+
+const (
+	signatureVersion = "v0"
+	// 재전송 공격을 막기 위해 이보다 오래된 요청은 거부한다
+	maxRequestAge = 5 * time.Minute
+)
+
+var (
+	ErrNotConfigured    = errors.New("slack signing secret is not configured")
+	ErrInvalidSignature = errors.New("invalid slack request signature")
+	ErrStaleRequest     = errors.New("slack request timestamp is too old")
+)
+
+// Verify 는 X-Slack-Request-Timestamp, X-Slack-Signature 헤더와 원본 body 로 Slack 서명을 검증한다.
+// https://api.slack.com/authentication/verifying-requests-from-slack
+func (h *Handler) Verify(timestamp, signature string, body []byte) error {
+	if h.options.SigningSecret == "" {
+		return ErrNotConfigured
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	age := time.Since(time.Unix(seconds, 0))
+	if age > maxRequestAge || age < -maxRequestAge {
+		return ErrStaleRequest
+	}
+
+	mac := hmac.New(sha256.New, []byte(h.options.SigningSecret))
+	mac.Write([]byte(signatureVersion + ":" + timestamp + ":"))
+	mac.Write(body)
+	expected := signatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
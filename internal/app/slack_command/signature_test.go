@@ -0,0 +1,51 @@
+package slack_command
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signatureVersion + ":" + timestamp + ":"))
+	mac.Write(body)
+	return signatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerify(t *testing.T) {
+	const secret = "signing-secret"
+	body := []byte("command=%2Fk8s&text=node-memory")
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-maxRequestAge-time.Minute).Unix(), 10)
+	future := strconv.FormatInt(time.Now().Add(maxRequestAge+time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		want      error
+	}{
+		{"valid signature", secret, now, sign(secret, now, body), body, nil},
+		{"wrong secret", secret, now, sign("other-secret", now, body), body, ErrInvalidSignature},
+		{"tampered body", secret, now, sign(secret, now, body), []byte("command=%2Fk8s&text=drain"), ErrInvalidSignature},
+		{"stale timestamp", secret, old, sign(secret, old, body), body, ErrStaleRequest},
+		{"future timestamp", secret, future, sign(secret, future, body), body, ErrStaleRequest},
+		{"non-numeric timestamp", secret, "yesterday", sign(secret, "yesterday", body), body, ErrInvalidSignature},
+		{"empty secret", "", now, sign("", now, body), body, ErrNotConfigured},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{options: Options{SigningSecret: tt.secret}}
+			if err := h.Verify(tt.timestamp, tt.signature, tt.body); !errors.Is(err, tt.want) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package slack_command
+
+import (
+	"bytes"
+	"client-go/internal/app/pod_metadata"
+	"client-go/internal/pkg/notifier"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	actionConfirm = "confirm"
+	actionCancel  = "cancel"
+
+	responseTimeout = 10 * time.Second
+)
+
+type Options struct {
+	SigningSecret string
+	// AllowedUserIDs 에 있는 사용자만 되돌릴 수 없는 명령을 실행할 수 있다. 비어 있으면 아무도 실행할 수 없다
+	AllowedUserIDs []string
+}
+
+// OptionsFromEnv 는 SLACK_SIGNING_SECRET, SLACK_ALLOWED_USERS(쉼표 구분 user ID) 로 옵션을 만든다.
+// SLACK_SIGNING_SECRET 이 비어 있으면 모든 요청을, SLACK_ALLOWED_USERS 가 비어 있으면 되돌릴 수 없는 명령을 거부한다
+func OptionsFromEnv() Options {
+	return Options{
+		SigningSecret:  os.Getenv("SLACK_SIGNING_SECRET"),
+		AllowedUserIDs: pod_metadata.SplitList(os.Getenv("SLACK_ALLOWED_USERS")),
+	}
+}
+
+// allows 는 userID 가 cmd 를 실행할 수 있는지 확인한다
+func (o Options) allows(cmd commandType, userID string) bool {
+	return !cmd.Destructive || slices.Contains(o.AllowedUserIDs, userID)
+}
+
+// Handler 는 Slack slash command 와 interactive action 요청을 처리한다.
+// Slack 은 3초 안에 응답을 받아야 하므로 명령은 비동기로 실행하고 결과는 response_url 로 보낸다
+type Handler struct {
+	clientSet *kubernetes.Clientset
+	notifier  notifier.Notifier
+	options   Options
+	client    *http.Client
+
+	mu sync.Mutex
+	// handled 는 처리한 action 의 key 와 처리 시각. 서명 검증이 maxRequestAge 까지 받아주므로 그동안 기억한다
+	handled map[string]time.Time
+}
+
+// CommandRequest 는 slash command 요청의 form 값 중 사용하는 것
+type CommandRequest struct {
+	Command     string
+	Text        string
+	UserID      string
+	UserName    string
+	ResponseURL string
+}
+
+// actionPayloadType 은 interactive action 요청의 payload(JSON) 중 사용하는 것
+type actionPayloadType struct {
+	Type      string `json:"type"`
+	TriggerID string `json:"trigger_id"`
+	User      struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	ResponseURL string `json:"response_url"`
+	Actions     []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+		ActionTS string `json:"action_ts"`
+	} `json:"actions"`
+}
+
+func NewHandler(clientSet *kubernetes.Clientset, n notifier.Notifier, options Options) *Handler {
+	if options.SigningSecret != "" && len(options.AllowedUserIDs) == 0 {
+		log.Warn("SLACK_ALLOWED_USERS is not set, destructive slack commands are disabled")
+	}
+	return &Handler{
+		clientSet: clientSet,
+		notifier:  n,
+		options:   options,
+		client:    &http.Client{Timeout: responseTimeout},
+		handled:   map[string]time.Time{},
+	}
+}
+
+// HandleCommand 는 slash command 에 바로 돌려줄 응답을 만든다.
+// 되돌릴 수 없는 명령은 실행하지 않고 확인 버튼을 보여준다
+func (h *Handler) HandleCommand(req CommandRequest) map[string]interface{} {
+	name, args := parseCommandText(req.Text)
+	cmd, ok := findCommand(name)
+	if !ok {
+		return ephemeral(helpText(req.Command))
+	}
+	if err := cmd.validate(args); err != nil {
+		return ephemeral(fmt.Sprintf(":x: %v\nUsage: `%s %s`", err, req.Command, cmd.Usage))
+	}
+
+	text := strings.TrimSpace(req.Text)
+	if !h.options.allows(cmd, req.UserID) {
+		return ephemeral(fmt.Sprintf(":no_entry: You are not allowed to run `%s`", text))
+	}
+	if cmd.Destructive {
+		return confirmation(cmd, text)
+	}
+	go h.run(cmd, args, text, req.UserID, req.ResponseURL)
+	return ephemeral(fmt.Sprintf(":hourglass_flowing_sand: Running `%s`...", text))
+}
+
+// HandleAction 은 확인 버튼 클릭을 처리한다. 결과는 모두 response_url 로 보낸다.
+// 같은 trigger_id, action_ts 로 다시 온 요청은 무시한다
+func (h *Handler) HandleAction(payload string) error {
+	var action actionPayloadType
+	if err := json.Unmarshal([]byte(payload), &action); err != nil {
+		return fmt.Errorf("invalid action payload: %w", err)
+	}
+	if action.Type != "block_actions" || len(action.Actions) == 0 {
+		return errors.New("unsupported action payload")
+	}
+
+	selected := action.Actions[0]
+	if key := action.TriggerID + ":" + selected.ActionTS; key != ":" && !h.markHandled(key) {
+		log.Warnf("Ignoring replayed slack action %q from %s", selected.ActionID, action.User.ID)
+		return nil
+	}
+	text := strings.TrimSpace(selected.Value)
+	name, args := parseCommandText(text)
+	cmd, ok := findCommand(name)
+	if !ok {
+		return fmt.Errorf("unknown command %q", name)
+	}
+	if err := cmd.validate(args); err != nil {
+		return err
+	}
+
+	switch selected.ActionID {
+	case actionCancel:
+		h.respond(action.ResponseURL, map[string]interface{}{
+			"replace_original": true,
+			"text":             fmt.Sprintf("Cancelled `%s` (<@%s>)", text, action.User.ID),
+		})
+		return nil
+	case actionConfirm:
+		if !h.options.allows(cmd, action.User.ID) {
+			h.respond(action.ResponseURL, map[string]interface{}{
+				"replace_original": true,
+				"text":             fmt.Sprintf(":no_entry: <@%s> is not allowed to run `%s`", action.User.ID, text),
+			})
+			return nil
+		}
+		h.respond(action.ResponseURL, map[string]interface{}{
+			"replace_original": true,
+			"text":             fmt.Sprintf(":hourglass_flowing_sand: <@%s> confirmed `%s`, running...", action.User.ID, text),
+		})
+		go h.run(cmd, args, text, action.User.ID, action.ResponseURL)
+		return nil
+	}
+	return fmt.Errorf("unknown action %q", selected.ActionID)
+}
+
+// markHandled 는 key 를 처리한 것으로 기록하고, 이미 처리한 key 면 false 를 돌려준다
+func (h *Handler) markHandled(key string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	for handledKey, handledAt := range h.handled {
+		if now.Sub(handledAt) > maxRequestAge {
+			delete(h.handled, handledKey)
+		}
+	}
+	if _, ok := h.handled[key]; ok {
+		return false
+	}
+	h.handled[key] = now
+	return true
+}
+
+// run 은 명령을 실행하고 결과를 response_url 로 보낸다. 되돌릴 수 없는 명령의 결과는 채널 전체에 보이게 하고 알림도 남긴다
+func (h *Handler) run(cmd commandType, args []string, text, userID, responseURL string) {
+	log.Infof("Running slack command %q requested by %s", text, userID)
+	result, err := cmd.run(h, args)
+	severity := notifier.SeverityInfo
+	if err != nil {
+		log.WithError(err).Errorf("Slack command %q failed", text)
+		result = fmt.Sprintf(":x: `%s` failed: %v", text, err)
+		severity = notifier.SeverityCritical
+	}
+
+	response := ephemeral(result)
+	if cmd.Destructive {
+		response["response_type"] = "in_channel"
+		notifyErr := h.notifier.Notify(context.Background(), notifier.Message{
+			Event:    "slack-command." + cmd.Name,
+			Title:    "Slack Command Executed",
+			Text:     fmt.Sprintf("`%s` requested by <@%s>\n%s", text, userID, result),
+			Severity: severity,
+		})
+		if notifyErr != nil {
+			log.WithError(notifyErr).Error("Failed to send slack command notification")
+		}
+	}
+	h.respond(responseURL, response)
+}
+
+func (h *Handler) respond(responseURL string, response map[string]interface{}) {
+	if responseURL == "" {
+		return
+	}
+	body, err := json.Marshal(response)
+	if err != nil {
+		log.WithError(err).Error("Failed to encode slack response")
+		return
+	}
+	res, err := h.client.Post(responseURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.WithError(err).Error("Failed to send slack response")
+		return
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		log.Errorf("Slack response_url returned %s", res.Status)
+	}
+}
+
+func parseCommandText(text string) (string, []string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return strings.ToLower(fields[0]), fields[1:]
+}
+
+func ephemeral(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"response_type": "ephemeral",
+		"text":          text,
+	}
+}
+
+// confirmation 은 확인/취소 버튼이 붙은 메시지. 버튼 value 에 실행할 명령을 그대로 담는다
+func confirmation(cmd commandType, text string) map[string]interface{} {
+	prompt := fmt.Sprintf(":warning: `%s` will %s. Are you sure?", text, cmd.Description)
+	plainText := func(value string) map[string]string {
+		return map[string]string{"type": "plain_text", "text": value}
+	}
+	return map[string]interface{}{
+		"response_type": "ephemeral",
+		"text":          prompt,
+		"blocks": []map[string]interface{}{
+			{
+				"type": "section",
+				"text": map[string]string{"type": "mrkdwn", "text": prompt},
+			},
+			{
+				"type": "actions",
+				"elements": []map[string]interface{}{
+					{
+						"type":      "button",
+						"text":      plainText("Confirm"),
+						"style":     "danger",
+						"action_id": actionConfirm,
+						"value":     text,
+					},
+					{
+						"type":      "button",
+						"text":      plainText("Cancel"),
+						"action_id": actionCancel,
+						"value":     text,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,103 @@
+package slack_command
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newResponseServer 는 response_url 로 온 메시지의 text 를 채널로 넘기는 테스트 서버
+func newResponseServer(t *testing.T) (*httptest.Server, <-chan string) {
+	t.Helper()
+	texts := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		_ = json.Unmarshal(data, &body)
+		text, _ := body["text"].(string)
+		texts <- text
+	}))
+	t.Cleanup(server.Close)
+	return server, texts
+}
+
+func actionPayload(t *testing.T, actionID, value, userID, responseURL string) string {
+	t.Helper()
+	payload := map[string]interface{}{
+		"type":         "block_actions",
+		"trigger_id":   "trigger-1",
+		"user":         map[string]string{"id": userID},
+		"response_url": responseURL,
+		"actions":      []map[string]string{{"action_id": actionID, "value": value, "action_ts": "1700000000.000001"}},
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func receive(t *testing.T, texts <-chan string) string {
+	t.Helper()
+	select {
+	case text := <-texts:
+		return text
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a slack response")
+		return ""
+	}
+}
+
+func TestHandleActionIgnoresReplays(t *testing.T) {
+	server, texts := newResponseServer(t)
+	h := NewHandler(nil, nil, Options{})
+	payload := actionPayload(t, actionCancel, "drain 70", "U1", server.URL)
+
+	if err := h.HandleAction(payload); err != nil {
+		t.Fatal(err)
+	}
+	if text := receive(t, texts); !strings.HasPrefix(text, "Cancelled `drain 70`") {
+		t.Errorf("response = %q", text)
+	}
+	if err := h.HandleAction(payload); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case text := <-texts:
+		t.Errorf("replayed action was handled again: %q", text)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDestructiveCommandsRequireAllowedUser(t *testing.T) {
+	h := NewHandler(nil, nil, Options{AllowedUserIDs: []string{"U1"}})
+
+	response := h.HandleCommand(CommandRequest{Command: "/k8s", Text: "drain", UserID: "U2"})
+	if text := response["text"].(string); !strings.Contains(text, "not allowed") {
+		t.Errorf("HandleCommand for a denied user = %q", text)
+	}
+	response = h.HandleCommand(CommandRequest{Command: "/k8s", Text: "drain", UserID: "U1"})
+	if _, ok := response["blocks"]; !ok {
+		t.Errorf("HandleCommand for an allowed user = %v, want a confirmation", response)
+	}
+
+	server, texts := newResponseServer(t)
+	if err := h.HandleAction(actionPayload(t, actionConfirm, "drain", "U2", server.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if text := receive(t, texts); !strings.Contains(text, "not allowed") {
+		t.Errorf("confirm by a denied user = %q", text)
+	}
+}
+
+func TestDestructiveCommandsDeniedWithoutAllowlist(t *testing.T) {
+	h := NewHandler(nil, nil, Options{})
+	response := h.HandleCommand(CommandRequest{Command: "/k8s", Text: "evicted-pods-clean", UserID: "U1"})
+	if text := response["text"].(string); !strings.Contains(text, "not allowed") {
+		t.Errorf("HandleCommand without an allowlist = %q", text)
+	}
+}
@@ -0,0 +1,245 @@
+package snapshot
+
+import (
+	"client-go/internal/app/pod_metadata"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+)
+
+type workloadRefType struct {
+	Namespace string
+	Kind      string
+	Name      string
+}
+
+type fieldChangeType struct {
+	Container string
+	Field     string
+	Old       string
+	New       string
+}
+
+type workloadChangeType struct {
+	workloadRefType
+	Changes []fieldChangeType
+}
+
+type Diff struct {
+	From    string
+	To      string
+	Added   []workloadRefType
+	Removed []workloadRefType
+	Changed []workloadChangeType
+}
+
+type totalsType struct {
+	ID               string
+	TakenAt          time.Time
+	Workloads        int
+	Containers       int
+	CPURequestCores  float64
+	CPULimitCores    float64
+	MemoryRequestGiB float64
+	MemoryLimitGiB   float64
+}
+
+// Take 는 현재 인벤토리를 스냅샷으로 저장
+func Take(clientSet kubernetes.Interface, store Store, opts pod_metadata.ReportOptions) (Snapshot, error) {
+	inventory, err := pod_metadata.GetPodMetadata(clientSet, opts)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return store.Save(inventory, opts.Scope, time.Now())
+}
+
+// RunPeriodically 는 interval 마다 스냅샷을 저장한다. 실패해도 다음 주기에 다시 시도
+func RunPeriodically(clientSet kubernetes.Interface, store Store, opts pod_metadata.ReportOptions, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		snap, err := Take(clientSet, store, opts)
+		if err != nil {
+			log.WithError(err).Error("Failed to take inventory snapshot")
+			continue
+		}
+		log.Info("Inventory snapshot saved: ", snap.ID)
+	}
+}
+
+// Compare 는 두 스냅샷 사이에 추가/삭제된 워크로드와 컨테이너 이미지, requests/limits, replicas 변경을 찾는다
+func Compare(from, to Snapshot) Diff {
+	diff := Diff{From: from.ID, To: to.ID}
+
+	old := indexWorkloads(from.Inventory)
+	current := indexWorkloads(to.Inventory)
+
+	for _, w := range from.Inventory.Workloads {
+		ref := workloadRefType{Namespace: w.Namespace, Kind: w.WorkloadKind, Name: w.WorkloadName}
+		if _, ok := current[ref]; !ok {
+			diff.Removed = append(diff.Removed, ref)
+		}
+	}
+
+	for _, w := range to.Inventory.Workloads {
+		ref := workloadRefType{Namespace: w.Namespace, Kind: w.WorkloadKind, Name: w.WorkloadName}
+		prev, ok := old[ref]
+		if !ok {
+			diff.Added = append(diff.Added, ref)
+			continue
+		}
+
+		var changes []fieldChangeType
+		if prev.replicas != w.Replicas {
+			changes = append(changes, fieldChangeType{Field: "Replicas", Old: strconv.Itoa(int(prev.replicas)), New: strconv.Itoa(int(w.Replicas))})
+		}
+
+		seen := map[string]bool{}
+		for _, c := range w.Containers {
+			seen[c.Name] = true
+			prevFields, ok := prev.containers[c.Name]
+			if !ok {
+				changes = append(changes, fieldChangeType{Container: c.Name, Field: "Container", New: "added"})
+				continue
+			}
+			for i, field := range c.Fields() {
+				if prevFields[i][1] != field[1] {
+					changes = append(changes, fieldChangeType{Container: c.Name, Field: field[0], Old: prevFields[i][1], New: field[1]})
+				}
+			}
+		}
+		for _, name := range prev.containerOrder {
+			if !seen[name] {
+				changes = append(changes, fieldChangeType{Container: name, Field: "Container", Old: "removed"})
+			}
+		}
+
+		if len(changes) > 0 {
+			diff.Changed = append(diff.Changed, workloadChangeType{workloadRefType: ref, Changes: changes})
+		}
+	}
+	return diff
+}
+
+type indexedWorkload struct {
+	replicas       int32
+	containers     map[string][][2]string
+	containerOrder []string
+}
+
+func indexWorkloads(inventory pod_metadata.ResourceReport) map[workloadRefType]indexedWorkload {
+	index := map[workloadRefType]indexedWorkload{}
+	for _, w := range inventory.Workloads {
+		entry := indexedWorkload{replicas: w.Replicas, containers: map[string][][2]string{}}
+		for _, c := range w.Containers {
+			entry.containers[c.Name] = c.Fields()
+			entry.containerOrder = append(entry.containerOrder, c.Name)
+		}
+		index[workloadRefType{Namespace: w.Namespace, Kind: w.WorkloadKind, Name: w.WorkloadName}] = entry
+	}
+	return index
+}
+
+// Totals 는 스냅샷의 파드 합계 × replicas 를 모두 더한 값
+func Totals(snap Snapshot) totalsType {
+	totals := totalsType{ID: snap.ID, TakenAt: snap.TakenAt}
+	for _, w := range snap.Inventory.Workloads {
+		replicas := float64(w.Replicas)
+		totals.Workloads++
+		totals.Containers += len(w.Containers)
+		totals.CPURequestCores += quantity(w.PodTotals.CPURequest) * replicas
+		totals.CPULimitCores += quantity(w.PodTotals.CPULimit) * replicas
+		totals.MemoryRequestGiB += quantity(w.PodTotals.MemoryRequest) * replicas / (1 << 30)
+		totals.MemoryLimitGiB += quantity(w.PodTotals.MemoryLimit) * replicas / (1 << 30)
+	}
+	totals.CPURequestCores = round(totals.CPURequestCores)
+	totals.CPULimitCores = round(totals.CPULimitCores)
+	totals.MemoryRequestGiB = round(totals.MemoryRequestGiB)
+	totals.MemoryLimitGiB = round(totals.MemoryLimitGiB)
+	return totals
+}
+
+// History 는 저장된 모든 스냅샷의 합계를 시간순으로 반환
+func History(store Store) ([]totalsType, error) {
+	ids, err := store.IDs()
+	if err != nil {
+		return nil, err
+	}
+	history := make([]totalsType, 0, len(ids))
+	for _, id := range ids {
+		snap, err := store.Load(id)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, Totals(snap))
+	}
+	return history, nil
+}
+
+// DiffByID 는 from/to ID 로 스냅샷을 비교한다. to 가 비어 있으면 최신, from 이 비어 있으면 to 이전의 같은 scope 스냅샷 중 가장 최근 것을 사용.
+// scope 가 다르면 namespace 가 빠지거나 더해진 것이 추가/삭제로 보이므로 비교하지 않는다
+func DiffByID(store Store, fromID, toID string) (Diff, error) {
+	ids, err := store.IDs()
+	if err != nil {
+		return Diff{}, err
+	}
+	if toID == "" {
+		if len(ids) == 0 {
+			return Diff{}, fmt.Errorf("no snapshots available")
+		}
+		toID = ids[len(ids)-1]
+	}
+	to, err := store.Load(toID)
+	if err != nil {
+		return Diff{}, err
+	}
+	if fromID == "" {
+		return previousDiff(store, ids, to)
+	}
+
+	from, err := store.Load(fromID)
+	if err != nil {
+		return Diff{}, err
+	}
+	if !from.Scope.Equal(to.Scope) {
+		return Diff{}, fmt.Errorf("snapshots %s and %s were taken with different namespace scopes", fromID, toID)
+	}
+	return Compare(from, to), nil
+}
+
+// previousDiff 는 to 이전 스냅샷 중 scope 가 같은 가장 최근 것과 비교한다
+func previousDiff(store Store, ids []string, to Snapshot) (Diff, error) {
+	for i := len(ids) - 1; i >= 0; i-- {
+		if ids[i] >= to.ID {
+			continue
+		}
+		from, err := store.Load(ids[i])
+		if err != nil {
+			return Diff{}, err
+		}
+		if from.Scope.Equal(to.Scope) {
+			return Compare(from, to), nil
+		}
+	}
+	return Diff{}, fmt.Errorf("no snapshot with the same namespace scope before %s", to.ID)
+}
+
+func quantity(value string) float64 {
+	if value == "" {
+		return 0
+	}
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0
+	}
+	return q.AsApproximateFloat64()
+}
+
+func round(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
@@ -0,0 +1,123 @@
+package snapshot
+
+import (
+	"client-go/internal/app/pod_metadata"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDir = "./snapshots"
+	filePrefix = "inventory-"
+	fileSuffix = ".json"
+	// 같은 초에 찍힌 스냅샷이 서로 덮어쓰지 않도록 나노초까지 ID 에 넣는다. 고정 폭이라 문자열 정렬이 시간순이다
+	idLayout = "20060102T150405.000000000Z"
+)
+
+// Snapshot 은 특정 시점의 워크로드 리소스 인벤토리. Scope 가 다른 스냅샷끼리는 비교하지 않는다
+type Snapshot struct {
+	ID        string
+	TakenAt   time.Time
+	Scope     pod_metadata.NamespaceScope
+	Inventory pod_metadata.ResourceReport
+}
+
+type Store struct {
+	Dir string
+}
+
+// NewStoreFromEnv 는 SNAPSHOT_DIR(기본 ./snapshots) 에 스냅샷을 저장하는 Store 를 만든다
+func NewStoreFromEnv() Store {
+	dir := os.Getenv("SNAPSHOT_DIR")
+	if dir == "" {
+		dir = defaultDir
+	}
+	return Store{Dir: dir}
+}
+
+func (s Store) Save(inventory pod_metadata.ResourceReport, scope pod_metadata.NamespaceScope, takenAt time.Time) (Snapshot, error) {
+	snap := Snapshot{
+		ID:        takenAt.UTC().Format(idLayout),
+		TakenAt:   takenAt.UTC(),
+		Scope:     scope,
+		Inventory: inventory,
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return Snapshot{}, err
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	// 쓰는 도중 읽히지 않도록 임시 파일에 쓴 뒤 링크한다. rename 과 달리 link 는 같은 ID 의 스냅샷이 있으면 실패한다
+	tmp := filepath.Join(s.Dir, "."+filePrefix+snap.ID+fileSuffix)
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		os.Remove(tmp)
+		return Snapshot{}, err
+	}
+	defer os.Remove(tmp)
+	if err := os.Link(tmp, s.path(snap.ID)); err != nil {
+		if os.IsExist(err) {
+			return Snapshot{}, fmt.Errorf("snapshot %s already exists", snap.ID)
+		}
+		return Snapshot{}, err
+	}
+	return snap, nil
+}
+
+// IDs 는 저장된 스냅샷 ID 를 오래된 순서로 반환
+func (s Store) IDs() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+	}
+	// ID 가 시간 형식이므로 문자열 정렬이 곧 시간순 정렬
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (s Store) Load(id string) (Snapshot, error) {
+	if !validID(id) {
+		return Snapshot{}, fmt.Errorf("invalid snapshot id %q", id)
+	}
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Snapshot{}, fmt.Errorf("snapshot %s not found", id)
+		}
+		return Snapshot{}, err
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return Snapshot{}, fmt.Errorf("could not read snapshot %s: %w", id, err)
+	}
+	return snap, nil
+}
+
+func validID(id string) bool {
+	_, err := time.Parse(idLayout, id)
+	return err == nil
+}
+
+func (s Store) path(id string) string {
+	return filepath.Join(s.Dir, filePrefix+id+fileSuffix)
+}
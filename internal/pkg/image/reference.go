@@ -0,0 +1,54 @@
+package image
+
+import "strings"
+
+const defaultRegistry = "docker.io"
+
+// Reference 는 컨테이너 이미지 문자열을 registry/repository/tag/digest 로 나눈 결과
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// Parse 는 "registry/repo:tag@sha256:..." 형태의 이미지 문자열을 나눈다.
+// 첫 번째 경로가 '.' 이나 ':' 을 포함하거나 localhost 이면 registry 로 보고, 아니면 docker.io 로 간주
+func Parse(ref string) Reference {
+	var parsed Reference
+
+	if i := strings.Index(ref, "@"); i >= 0 {
+		parsed.Digest = ref[i+1:]
+		ref = ref[:i]
+	}
+
+	// 마지막 '/' 뒤의 ':' 만 태그 구분자다(registry 포트와 구분)
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		parsed.Tag = ref[i+1:]
+		ref = ref[:i]
+	}
+
+	parsed.Registry = defaultRegistry
+	if i := strings.Index(ref, "/"); i >= 0 {
+		first := ref[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			parsed.Registry = first
+			ref = ref[i+1:]
+		}
+	}
+	parsed.Repository = ref
+	return parsed
+}
+
+// IsPinned 는 digest 로 고정된 이미지인지 확인
+func (r Reference) IsPinned() bool {
+	return r.Digest != ""
+}
+
+// IsFloating 은 태그와 digest 가 모두 없거나 latest 태그인 이미지인지 확인
+func (r Reference) IsFloating() bool {
+	if r.IsPinned() {
+		return false
+	}
+	return r.Tag == "" || r.Tag == "latest"
+}
@@ -0,0 +1,34 @@
+package image
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		ref      string
+		want     Reference
+		pinned   bool
+		floating bool
+	}{
+		{"nginx", Reference{Registry: "docker.io", Repository: "nginx"}, false, true},
+		{"nginx:latest", Reference{Registry: "docker.io", Repository: "nginx", Tag: "latest"}, false, true},
+		{"library/nginx:1.25", Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25"}, false, false},
+		{"ghcr.io/org/app:v1", Reference{Registry: "ghcr.io", Repository: "org/app", Tag: "v1"}, false, false},
+		{"localhost/app:1.0", Reference{Registry: "localhost", Repository: "app", Tag: "1.0"}, false, false},
+		{"registry.local:5000/team/app", Reference{Registry: "registry.local:5000", Repository: "team/app"}, false, true},
+		{"registry.local:5000/team/app:1.2.3", Reference{Registry: "registry.local:5000", Repository: "team/app", Tag: "1.2.3"}, false, false},
+		{"nginx@sha256:abc", Reference{Registry: "docker.io", Repository: "nginx", Digest: "sha256:abc"}, true, false},
+		{"registry.local:5000/app:1.0@sha256:abc", Reference{Registry: "registry.local:5000", Repository: "app", Tag: "1.0", Digest: "sha256:abc"}, true, false},
+		{"nginx:latest@sha256:abc", Reference{Registry: "docker.io", Repository: "nginx", Tag: "latest", Digest: "sha256:abc"}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got := Parse(tt.ref)
+			if got != tt.want {
+				t.Errorf("Parse = %+v, want %+v", got, tt.want)
+			}
+			if got.IsPinned() != tt.pinned || got.IsFloating() != tt.floating {
+				t.Errorf("IsPinned = %v, IsFloating = %v, want %v, %v", got.IsPinned(), got.IsFloating(), tt.pinned, tt.floating)
+			}
+		})
+	}
+}
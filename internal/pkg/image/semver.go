@@ -0,0 +1,45 @@
+package image
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Version 은 태그에서 읽은 semver 의 숫자 부분
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion 은 "v1.2.3", "1.2", "v2", "1.2.3-alpine" 같은 태그에서 버전을 읽는다.
+// 숫자로 시작하지 않는 태그(latest, stable, 커밋 해시 등)와 점도 v 도 없는 숫자(1233 같은 빌드 번호, 날짜)는 ok=false
+func ParseVersion(tag string) (Version, bool) {
+	prefixed := strings.HasPrefix(tag, "v")
+	tag = strings.TrimPrefix(tag, "v")
+	if i := strings.IndexAny(tag, "-+_"); i >= 0 {
+		tag = tag[:i]
+	}
+	parts := strings.Split(tag, ".")
+	if len(parts) > 3 {
+		return Version{}, false
+	}
+
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, false
+		}
+		numbers[i] = n
+	}
+	if len(parts) == 1 && !prefixed {
+		return Version{}, false
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, true
+}
+
+// Version 은 태그에서 읽은 버전을 반환
+func (r Reference) Version() (Version, bool) {
+	return ParseVersion(r.Tag)
+}
@@ -0,0 +1,36 @@
+package image
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want Version
+		ok   bool
+	}{
+		{"1.2.3", Version{1, 2, 3}, true},
+		{"v1.2.3", Version{1, 2, 3}, true},
+		{"1.2", Version{1, 2, 0}, true},
+		{"v1", Version{1, 0, 0}, true},
+		{"1.2.3-rc1", Version{1, 2, 3}, true},
+		{"1.25.3-alpine", Version{1, 25, 3}, true},
+		{"2.0.0+build.5", Version{2, 0, 0}, true},
+		{"1", Version{}, false},
+		{"1233", Version{}, false},
+		{"20240101", Version{}, false},
+		{"latest", Version{}, false},
+		{"stable", Version{}, false},
+		{"", Version{}, false},
+		{"1.2.3.4", Version{}, false},
+		{"a1b2c3d", Version{}, false},
+		{"v", Version{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, ok := ParseVersion(tt.tag)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("ParseVersion = (%+v, %v), want (%+v, %v)", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	appsV1 "k8s.io/api/apps/v1"
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/pager"
+)
+
+const defaultPageSize = 500
+
+// pageSize 는 KUBE_LIST_PAGE_SIZE 환경 변수로 한 번에 가져올 오브젝트 수를 지정
+func pageSize() int64 {
+	if size, err := strconv.ParseInt(os.Getenv("KUBE_LIST_PAGE_SIZE"), 10, 64); err == nil && size > 0 {
+		return size
+	}
+	return defaultPageSize
+}
+
+// eachItem 은 Limit/Continue 로 목록을 페이지 단위로 가져오면서 아이템마다 fn 을 호출한다.
+// 전체 목록을 한 번에 메모리에 올리지 않으므로 클러스터 크기와 상관없이 사용하는 메모리가 일정하다.
+func eachItem[T any](ctx context.Context, opts metav1.ListOptions, list pager.ListPageFunc, fn func(*T) error) error {
+	p := pager.New(list)
+	p.PageSize = pageSize()
+	p.PageBufferSize = 1
+	// continue 토큰이 만료됐을 때 전체 목록으로 fallback 하면 페이지네이션의 의미가 없어진다
+	p.FullListIfExpired = false
+
+	return p.EachListItemWithAlloc(ctx, opts, func(obj runtime.Object) error {
+		item, ok := any(obj).(*T)
+		if !ok {
+			return fmt.Errorf("unexpected list item type %T", obj)
+		}
+		return fn(item)
+	})
+}
+
+func EachPod(ctx context.Context, clientSet kubernetes.Interface, namespace string, opts metav1.ListOptions, fn func(*coreV1.Pod) error) error {
+	return eachItem(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		return clientSet.CoreV1().Pods(namespace).List(ctx, opts)
+	}, fn)
+}
+
+func EachNode(ctx context.Context, clientSet kubernetes.Interface, opts metav1.ListOptions, fn func(*coreV1.Node) error) error {
+	return eachItem(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		return clientSet.CoreV1().Nodes().List(ctx, opts)
+	}, fn)
+}
+
+func EachNamespace(ctx context.Context, clientSet kubernetes.Interface, opts metav1.ListOptions, fn func(*coreV1.Namespace) error) error {
+	return eachItem(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		return clientSet.CoreV1().Namespaces().List(ctx, opts)
+	}, fn)
+}
+
+func EachDeployment(ctx context.Context, clientSet kubernetes.Interface, namespace string, opts metav1.ListOptions, fn func(*appsV1.Deployment) error) error {
+	return eachItem(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		return clientSet.AppsV1().Deployments(namespace).List(ctx, opts)
+	}, fn)
+}
+
+// CountPods 는 조건에 맞는 파드 수만 센다
+func CountPods(ctx context.Context, clientSet kubernetes.Interface, namespace string, opts metav1.ListOptions) (int, error) {
+	count := 0
+	err := EachPod(ctx, clientSet, namespace, opts, func(*coreV1.Pod) error {
+		count++
+		return nil
+	})
+	return count, err
+}
+
+func EachStatefulSet(ctx context.Context, clientSet kubernetes.Interface, namespace string, opts metav1.ListOptions, fn func(*appsV1.StatefulSet) error) error {
+	return eachItem(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		return clientSet.AppsV1().StatefulSets(namespace).List(ctx, opts)
+	}, fn)
+}
+
+func EachDaemonSet(ctx context.Context, clientSet kubernetes.Interface, namespace string, opts metav1.ListOptions, fn func(*appsV1.DaemonSet) error) error {
+	return eachItem(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		return clientSet.AppsV1().DaemonSets(namespace).List(ctx, opts)
+	}, fn)
+}
+
+func EachReplicaSet(ctx context.Context, clientSet kubernetes.Interface, namespace string, opts metav1.ListOptions, fn func(*appsV1.ReplicaSet) error) error {
+	return eachItem(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		return clientSet.AppsV1().ReplicaSets(namespace).List(ctx, opts)
+	}, fn)
+}
+
+func EachReplicationController(ctx context.Context, clientSet kubernetes.Interface, namespace string, opts metav1.ListOptions, fn func(*coreV1.ReplicationController) error) error {
+	return eachItem(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		return clientSet.CoreV1().ReplicationControllers(namespace).List(ctx, opts)
+	}, fn)
+}
+
+func EachJob(ctx context.Context, clientSet kubernetes.Interface, namespace string, opts metav1.ListOptions, fn func(*batchV1.Job) error) error {
+	return eachItem(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		return clientSet.BatchV1().Jobs(namespace).List(ctx, opts)
+	}, fn)
+}
+
+func EachCronJob(ctx context.Context, clientSet kubernetes.Interface, namespace string, opts metav1.ListOptions, fn func(*batchV1.CronJob) error) error {
+	return eachItem(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		return clientSet.BatchV1().CronJobs(namespace).List(ctx, opts)
+	}, fn)
+}
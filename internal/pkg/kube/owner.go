@@ -0,0 +1,42 @@
+package kube
+
+import (
+	"strings"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OwnerWorkload 는 파드를 만든 최상위 워크로드의 kind 와 이름을 추정한다.
+// ReplicaSet 은 pod-template-hash 를 떼어 Deployment 로, 이름이 "-<숫자>" 로 끝나는 Job 은 CronJob 으로 본다.
+// API 를 추가로 호출하지 않기 위해 이름 규칙을 사용하므로 직접 만든 ReplicaSet/Job 은 잘못 분류될 수 있다.
+func OwnerWorkload(pod *coreV1.Pod) (kind, name string) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "Pod", pod.Name
+	}
+
+	switch owner.Kind {
+	case "ReplicaSet":
+		if hash := pod.Labels["pod-template-hash"]; hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+			return "Deployment", strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+	case "Job":
+		if i := strings.LastIndex(owner.Name, "-"); i > 0 && isDigits(owner.Name[i+1:]) {
+			return "CronJob", owner.Name[:i]
+		}
+	}
+	return owner.Kind, owner.Name
+}
+
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
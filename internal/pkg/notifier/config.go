@@ -0,0 +1,191 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	TypeSlack   = "slack"
+	TypeTeams   = "teams"
+	TypeDiscord = "discord"
+	TypeWebhook = "webhook"
+	TypeEmail   = "email"
+)
+
+// SinkConfig 는 알림을 보낼 곳 하나의 설정
+type SinkConfig struct {
+	Name    string
+	Type    string
+	URL     string
+	Headers map[string]string
+	SMTP    SMTPConfig
+	Filter  Filter
+	// QPS, Burst 가 0 이 아니면 이 대상에만 기본 rate limit 대신 적용한다
+	QPS   float32
+	Burst int
+}
+
+// New 는 설정에 맞는 backend 를 만들고, Filter 가 있으면 Filtered 로 감싼다
+func New(config SinkConfig) (Notifier, error) {
+	n, err := newBackend(config)
+	if err != nil {
+		return nil, err
+	}
+	return withFilter(n, config.Filter)
+}
+
+func withFilter(n Notifier, filter Filter) (Notifier, error) {
+	if filter.IsEmpty() {
+		return n, nil
+	}
+	return NewFiltered(n, filter)
+}
+
+func newBackend(config SinkConfig) (Notifier, error) {
+	if config.Name == "" {
+		config.Name = config.Type
+	}
+	switch config.Type {
+	case TypeSlack, TypeTeams, TypeDiscord, TypeWebhook:
+		if config.URL == "" {
+			return nil, fmt.Errorf("notifier %s: URL is required", config.Name)
+		}
+	}
+
+	switch config.Type {
+	case TypeSlack:
+		return NewSlack(config.Name, config.URL), nil
+	case TypeTeams:
+		return NewTeams(config.Name, config.URL), nil
+	case TypeDiscord:
+		return NewDiscord(config.Name, config.URL), nil
+	case TypeWebhook:
+		return NewWebhook(config.Name, config.URL, config.Headers), nil
+	case TypeEmail:
+		if config.SMTP.Host == "" || config.SMTP.From == "" || len(config.SMTP.To) == 0 {
+			return nil, fmt.Errorf("notifier %s: SMTP host, from and to are required", config.Name)
+		}
+		return NewEmail(config.Name, config.SMTP), nil
+	default:
+		return nil, fmt.Errorf("notifier %s: unknown type %q", config.Name, config.Type)
+	}
+}
+
+// SinksFromEnv 는 NOTIFIERS_FILE(JSON 배열) 과 backend 별 환경 변수로 알림 대상을 만든다.
+// route 별 Filter 는 NOTIFIERS_FILE 에서만 지정할 수 있다.
+//
+//	[{"Name": "prd-images", "Type": "slack", "URL": "...",
+//	  "Filter": {"Namespaces": ["prd-api"], "LabelSelector": "team=core", "ChangeTypes": ["image"]}}]
+//
+//	SLACK_WEBHOOK_URL, TEAMS_WEBHOOK_URL, DISCORD_WEBHOOK_URL,
+//	NOTIFY_WEBHOOK_URL(+ NOTIFY_WEBHOOK_HEADERS="Key=Value,..."),
+//	SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD, SMTP_FROM, SMTP_TO
+func SinksFromEnv() ([]SinkConfig, error) {
+	var sinks []SinkConfig
+	if path := os.Getenv("NOTIFIERS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read notifiers file: %w", err)
+		}
+		if err := json.Unmarshal(data, &sinks); err != nil {
+			return nil, fmt.Errorf("invalid notifiers file %s: %w", path, err)
+		}
+	}
+
+	for _, env := range []struct{ key, sinkType string }{
+		{"SLACK_WEBHOOK_URL", TypeSlack},
+		{"TEAMS_WEBHOOK_URL", TypeTeams},
+		{"DISCORD_WEBHOOK_URL", TypeDiscord},
+	} {
+		if url := os.Getenv(env.key); url != "" {
+			sinks = append(sinks, SinkConfig{Type: env.sinkType, URL: url})
+		}
+	}
+	if url := os.Getenv("NOTIFY_WEBHOOK_URL"); url != "" {
+		sinks = append(sinks, SinkConfig{Type: TypeWebhook, URL: url, Headers: parseHeaders(os.Getenv("NOTIFY_WEBHOOK_HEADERS"))})
+	}
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port, _ := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		sinks = append(sinks, SinkConfig{Type: TypeEmail, SMTP: SMTPConfig{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+			To:       splitList(os.Getenv("SMTP_TO")),
+		}})
+	}
+	return sinks, nil
+}
+
+// Dispatcher 는 설정된 모든 알림 대상을 묶는다. 대상마다 Filter 를 통과한 메시지를 템플릿으로 렌더링해 큐에 쌓고,
+// 큐가 rate limit, 재시도, digest 묶음을 처리한다
+type Dispatcher struct {
+	Multi
+	DeadLetters *DeadLetters
+}
+
+// FromEnv 는 환경 변수에 설정된 알림 대상으로 Dispatcher 를 만든다
+func FromEnv() (*Dispatcher, error) {
+	sinks, err := SinksFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := LoadTemplatesFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	options := QueueOptionsFromEnv()
+	dispatcher := &Dispatcher{DeadLetters: NewDeadLettersFromEnv()}
+	names := map[string]bool{}
+	for _, sink := range sinks {
+		backend, err := newBackend(sink)
+		if err != nil {
+			return nil, err
+		}
+		if names[backend.Name()] {
+			return nil, fmt.Errorf("duplicate notifier name %q", backend.Name())
+		}
+		names[backend.Name()] = true
+
+		sinkOptions := options
+		if sink.QPS > 0 {
+			sinkOptions.QPS = sink.QPS
+		}
+		if sink.Burst > 0 {
+			sinkOptions.Burst = sink.Burst
+		}
+		queued := NewQueued(backend, sinkOptions, dispatcher.DeadLetters)
+		n, err := withFilter(NewTemplated(queued, sink.Type, templates), sink.Filter)
+		if err != nil {
+			return nil, err
+		}
+		dispatcher.Multi = append(dispatcher.Multi, n)
+	}
+	return dispatcher, nil
+}
+
+func parseHeaders(value string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range splitList(value) {
+		if key, val, ok := strings.Cut(pair, "="); ok {
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+	}
+	return headers
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
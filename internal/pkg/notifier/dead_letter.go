@@ -0,0 +1,54 @@
+package notifier
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DeadLetter 는 재시도 끝에 끝내 보내지 못한 메시지
+type DeadLetter struct {
+	Destination string
+	Message     Message
+	Error       string
+	Attempts    int
+	FailedAt    time.Time
+}
+
+// DeadLetters 는 최근 dead letter 를 limit 개까지 메모리에 보관한다
+type DeadLetters struct {
+	mu    sync.Mutex
+	limit int
+	items []DeadLetter
+}
+
+// NewDeadLettersFromEnv 는 NOTIFY_DEAD_LETTER_LIMIT(기본 200) 개까지 보관하는 목록을 만든다
+func NewDeadLettersFromEnv() *DeadLetters {
+	limit := defaultDeadLetterLimit
+	if value, err := strconv.Atoi(os.Getenv("NOTIFY_DEAD_LETTER_LIMIT")); err == nil && value > 0 {
+		limit = value
+	}
+	return &DeadLetters{limit: limit}
+}
+
+func (d *DeadLetters) add(destination string, msg Message, err error, attempts int) {
+	letter := DeadLetter{Destination: destination, Message: msg, Attempts: attempts, FailedAt: time.Now()}
+	if err != nil {
+		letter.Error = err.Error()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.items = append(d.items, letter)
+	if len(d.items) > d.limit {
+		d.items = d.items[len(d.items)-d.limit:]
+	}
+}
+
+// List 는 보관 중인 dead letter 를 오래된 순서로 반환
+func (d *DeadLetters) List() []DeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]DeadLetter{}, d.items...)
+}
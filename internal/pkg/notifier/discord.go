@@ -0,0 +1,37 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+)
+
+const discordContentLimit = 2000
+
+type Discord struct {
+	name       string
+	webhookURL string
+	client     *http.Client
+}
+
+func NewDiscord(name, webhookURL string) *Discord {
+	return &Discord{name: name, webhookURL: webhookURL, client: newHTTPClient()}
+}
+
+func (d *Discord) Name() string {
+	return d.name
+}
+
+func (d *Discord) Notify(ctx context.Context, msg Message) error {
+	if len(msg.Payload) > 0 {
+		return postJSON(ctx, d.client, d.webhookURL, nil, msg.Payload)
+	}
+	content := msg.Text
+	if msg.Title != "" {
+		content = "**" + msg.Title + "**\n" + content
+	}
+	// Discord 는 content 가 2000자를 넘으면 요청을 거부한다
+	if runes := []rune(content); len(runes) > discordContentLimit {
+		content = string(runes[:discordContentLimit-3]) + "..."
+	}
+	return postJSON(ctx, d.client, d.webhookURL, nil, map[string]string{"content": content})
+}
@@ -0,0 +1,74 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Filter 는 알림 대상(route) 별로 받을 메시지를 고르는 조건.
+// namespace, label, change type 이 없는 메시지(예: node drain 알림)는 그 조건으로 걸러내지 않는다.
+type Filter struct {
+	Namespaces    []string
+	LabelSelector string
+	// ChangeTypes 중 하나라도 메시지의 ChangeTypes 에 있으면 보낸다 (예: image, resources, labels)
+	ChangeTypes []string
+}
+
+func (f Filter) IsEmpty() bool {
+	return len(f.Namespaces) == 0 && f.LabelSelector == "" && len(f.ChangeTypes) == 0
+}
+
+// Filtered 는 Filter 에 맞는 메시지만 내부 notifier 로 넘긴다
+type Filtered struct {
+	Notifier
+	filter   Filter
+	selector labels.Selector
+}
+
+func NewFiltered(n Notifier, filter Filter) (*Filtered, error) {
+	selector := labels.Everything()
+	if filter.LabelSelector != "" {
+		parsed, err := labels.Parse(filter.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %s: invalid label selector %q: %w", n.Name(), filter.LabelSelector, err)
+		}
+		selector = parsed
+	}
+	return &Filtered{Notifier: n, filter: filter, selector: selector}, nil
+}
+
+func (f *Filtered) Notify(ctx context.Context, msg Message) error {
+	if !f.Matches(msg) {
+		return nil
+	}
+	return f.Notifier.Notify(ctx, msg)
+}
+
+func (f *Filtered) Matches(msg Message) bool {
+	if msg.Namespace != "" && len(f.filter.Namespaces) > 0 && !contains(f.filter.Namespaces, msg.Namespace) {
+		return false
+	}
+	if len(msg.Labels) > 0 && !f.selector.Matches(labels.Set(msg.Labels)) {
+		return false
+	}
+	if len(msg.ChangeTypes) > 0 && len(f.filter.ChangeTypes) > 0 {
+		for _, changeType := range msg.ChangeTypes {
+			if contains(f.filter.ChangeTypes, changeType) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func contains(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package notifier
+
+import "testing"
+
+func TestFilteredMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		msg    Message
+		want   bool
+	}{
+		{"empty filter", Filter{}, Message{Namespace: "prd-api"}, true},
+		{"namespace match", Filter{Namespaces: []string{"prd-api"}}, Message{Namespace: "prd-api"}, true},
+		{"namespace mismatch", Filter{Namespaces: []string{"prd-api"}}, Message{Namespace: "dev-api"}, false},
+		{"no namespace on message", Filter{Namespaces: []string{"prd-api"}}, Message{}, true},
+		{"label match", Filter{LabelSelector: "team=core"}, Message{Labels: map[string]string{"team": "core"}}, true},
+		{"label mismatch", Filter{LabelSelector: "team=core"}, Message{Labels: map[string]string{"team": "data"}}, false},
+		{"no labels on message", Filter{LabelSelector: "team=core"}, Message{}, true},
+		{"negative selector match", Filter{LabelSelector: "team!=core"}, Message{Labels: map[string]string{"team": "data"}}, true},
+		{"change type match", Filter{ChangeTypes: []string{"image"}}, Message{ChangeTypes: []string{"resources", "image"}}, true},
+		{"change type mismatch", Filter{ChangeTypes: []string{"image"}}, Message{ChangeTypes: []string{"resources"}}, false},
+		{"no change types on message", Filter{ChangeTypes: []string{"image"}}, Message{}, true},
+		{
+			"all conditions",
+			Filter{Namespaces: []string{"prd-api"}, LabelSelector: "team=core", ChangeTypes: []string{"image"}},
+			Message{Namespace: "prd-api", Labels: map[string]string{"team": "core"}, ChangeTypes: []string{"image"}},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, err := NewFiltered(NewWebhook("webhook", "http://127.0.0.1:0", nil), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := filtered.Matches(tt.msg); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFilteredInvalidSelector(t *testing.T) {
+	if _, err := NewFiltered(NewWebhook("webhook", "http://127.0.0.1:0", nil), Filter{LabelSelector: "team in ("}); err == nil {
+		t.Fatal("expected an error for an invalid label selector")
+	}
+}
@@ -0,0 +1,102 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+
+	defaultTimeout = 10 * time.Second
+)
+
+// Message 는 backend 와 상관없는 알림 내용. Text 는 `code` 정도의 간단한 markdown 을 사용.
+// Namespace, Labels, ChangeTypes 는 route 별 Filter 에서 사용한다.
+// Data 는 템플릿에서 쓸 event 별 구조화된 값이고, Payload 는 템플릿이 만든 backend 용 JSON 으로 있으면 그대로 보낸다
+type Message struct {
+	Event    string
+	Title    string
+	Text     string
+	Severity string
+
+	Namespace   string
+	Labels      map[string]string
+	ChangeTypes []string
+
+	Data    interface{}
+	Payload json.RawMessage
+}
+
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Multi 는 여러 notifier 에 같은 메시지를 보낸다. 하나가 실패해도 나머지는 계속 보낸다
+type Multi []Notifier
+
+func (m Multi) Name() string {
+	return "multi"
+}
+
+func (m Multi) Notify(ctx context.Context, msg Message) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HTTPError 는 webhook 이 2xx 가 아닌 응답을 준 경우
+type HTTPError struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("webhook returned status code %d: %s", e.StatusCode, e.Body)
+}
+
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: defaultTimeout}
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload interface{}) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &HTTPError{StatusCode: resp.StatusCode, Header: resp.Header, Body: string(body)}
+	}
+	return nil
+}
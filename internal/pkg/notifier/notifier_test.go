@@ -0,0 +1,264 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type capturedRequest struct {
+	header http.Header
+	body   map[string]interface{}
+}
+
+// newCaptureServer 는 받은 요청을 채널로 넘기고 status 로 응답하는 테스트 서버
+func newCaptureServer(t *testing.T, status int) (*httptest.Server, <-chan capturedRequest) {
+	t.Helper()
+	requests := make(chan capturedRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("request body is not a JSON object: %s", data)
+		}
+		requests <- capturedRequest{header: r.Header, body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+var testMessage = Message{
+	Event:       "updated",
+	Title:       "Deployment Updated",
+	Text:        "line1\nline2",
+	Severity:    SeverityWarning,
+	Namespace:   "prd-api",
+	ChangeTypes: []string{"image"},
+}
+
+func TestSlackPayload(t *testing.T) {
+	server, requests := newCaptureServer(t, http.StatusOK)
+	if err := NewSlack("slack", server.URL).Notify(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	req := <-requests
+	if req.body["text"] != testMessage.Title {
+		t.Errorf("text = %v, want %q", req.body["text"], testMessage.Title)
+	}
+	blocks, ok := req.body["blocks"].([]interface{})
+	if !ok || len(blocks) != 1 {
+		t.Fatalf("blocks = %v, want one section", req.body["blocks"])
+	}
+	section := blocks[0].(map[string]interface{})
+	text := section["text"].(map[string]interface{})
+	if section["type"] != "section" || text["type"] != "mrkdwn" || text["text"] != "*Deployment Updated*\nline1\nline2" {
+		t.Errorf("unexpected section %v", section)
+	}
+}
+
+func TestTeamsPayload(t *testing.T) {
+	server, requests := newCaptureServer(t, http.StatusOK)
+	if err := NewTeams("teams", server.URL).Notify(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	req := <-requests
+	want := map[string]interface{}{
+		"@type":      "MessageCard",
+		"summary":    testMessage.Title,
+		"title":      testMessage.Title,
+		"themeColor": teamsThemeColors[SeverityWarning],
+		"text":       "line1  \nline2",
+	}
+	for key, value := range want {
+		if req.body[key] != value {
+			t.Errorf("%s = %v, want %v", key, req.body[key], value)
+		}
+	}
+}
+
+func TestDiscordPayload(t *testing.T) {
+	server, requests := newCaptureServer(t, http.StatusNoContent)
+	if err := NewDiscord("discord", server.URL).Notify(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	if req := <-requests; req.body["content"] != "**Deployment Updated**\nline1\nline2" {
+		t.Errorf("content = %v", req.body["content"])
+	}
+
+	long := testMessage
+	long.Title = ""
+	long.Text = strings.Repeat("가", discordContentLimit+10)
+	if err := NewDiscord("discord", server.URL).Notify(context.Background(), long); err != nil {
+		t.Fatal(err)
+	}
+	content := (<-requests).body["content"].(string)
+	if runes := []rune(content); len(runes) != discordContentLimit || !strings.HasSuffix(content, "...") {
+		t.Errorf("content has %d runes, want %d ending with ...", len(runes), discordContentLimit)
+	}
+}
+
+func TestWebhookPayload(t *testing.T) {
+	server, requests := newCaptureServer(t, http.StatusOK)
+	msg := testMessage
+	msg.Data = map[string]string{"name": "api"}
+	webhook := NewWebhook("webhook", server.URL, map[string]string{"Authorization": "Bearer token"})
+	if err := webhook.Notify(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	req := <-requests
+	if got := req.header.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Authorization header = %q", got)
+	}
+	for key, value := range map[string]string{"event": "updated", "title": msg.Title, "text": msg.Text, "severity": SeverityWarning, "namespace": "prd-api"} {
+		if req.body[key] != value {
+			t.Errorf("%s = %v, want %q", key, req.body[key], value)
+		}
+	}
+	if _, ok := req.body["timestamp"]; !ok {
+		t.Error("timestamp is missing")
+	}
+	if changeTypes, _ := req.body["changeTypes"].([]interface{}); len(changeTypes) != 1 || changeTypes[0] != "image" {
+		t.Errorf("changeTypes = %v", req.body["changeTypes"])
+	}
+	if data, _ := req.body["data"].(map[string]interface{}); data["name"] != "api" {
+		t.Errorf("data = %v", req.body["data"])
+	}
+}
+
+func TestTemplatePayloadIsSentAsIs(t *testing.T) {
+	server, requests := newCaptureServer(t, http.StatusOK)
+	msg := testMessage
+	msg.Payload = json.RawMessage(`{"custom":"payload"}`)
+	backends := []Notifier{
+		NewSlack("slack", server.URL),
+		NewTeams("teams", server.URL),
+		NewDiscord("discord", server.URL),
+		NewWebhook("webhook", server.URL, nil),
+	}
+	for _, backend := range backends {
+		if err := backend.Notify(context.Background(), msg); err != nil {
+			t.Fatalf("%s: %v", backend.Name(), err)
+		}
+		if req := <-requests; len(req.body) != 1 || req.body["custom"] != "payload" {
+			t.Errorf("%s sent %v, want the template payload", backend.Name(), req.body)
+		}
+	}
+}
+
+func TestHTTPErrorStatus(t *testing.T) {
+	server, _ := newCaptureServer(t, http.StatusTooManyRequests)
+	err := NewSlack("slack", server.URL).Notify(context.Background(), testMessage)
+	httpErr, ok := err.(*HTTPError)
+	if !ok || httpErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("err = %v, want HTTPError with 429", err)
+	}
+}
+
+// fakeSMTPServer 는 STARTTLS 와 AUTH 없이 메일 한 통을 받는 SMTP 서버 대역
+func fakeSMTPServer(t *testing.T) (host string, port int, received <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		text := textproto.NewConn(conn)
+		reply := func(line string) { _ = text.PrintfLine("%s", line) }
+
+		reply("220 localhost ESMTP")
+		var envelope []string
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.Fields(line + " ")[0])
+			switch command {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "MAIL", "RCPT":
+				envelope = append(envelope, line)
+				reply("250 OK")
+			case "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				data, err := text.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				messages <- strings.Join(envelope, "\n") + "\n\n" + string(data)
+				reply("250 OK")
+			case "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, messages
+}
+
+func TestEmailSendsPlainTextMail(t *testing.T) {
+	host, port, received := fakeSMTPServer(t)
+	email := NewEmail("email", SMTPConfig{
+		Host: host,
+		Port: port,
+		From: "alert@example.com",
+		To:   []string{"ops@example.com", "dev@example.com"},
+	})
+	if err := email.Notify(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	mail := <-received
+	reader := bufio.NewReader(strings.NewReader(mail))
+	for _, want := range []string{"MAIL FROM:<alert@example.com>", "RCPT TO:<ops@example.com>", "RCPT TO:<dev@example.com>"} {
+		line, _ := reader.ReadString('\n')
+		if !strings.HasPrefix(strings.TrimSpace(line), want) {
+			t.Errorf("envelope line = %q, want prefix %q", line, want)
+		}
+	}
+	for _, want := range []string{
+		"Subject: Deployment Updated",
+		"To: ops@example.com, dev@example.com",
+		"Content-Type: text/plain; charset=UTF-8",
+		"line1\nline2",
+	} {
+		if !strings.Contains(mail, want) {
+			t.Errorf("mail does not contain %q:\n%s", want, mail)
+		}
+	}
+}
+
+func TestEmailConnectionError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	email := NewEmail("email", SMTPConfig{Host: "127.0.0.1", Port: port, From: "a@example.com", To: []string{"b@example.com"}})
+	if err := email.Notify(context.Background(), testMessage); err == nil {
+		t.Fatalf("expected an error when nothing listens on port %s", strconv.Itoa(port))
+	}
+}
@@ -0,0 +1,257 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const (
+	defaultQueueQPS        = 1
+	defaultQueueBurst      = 3
+	defaultMaxRetries      = 5
+	defaultBaseBackoff     = time.Second
+	defaultMaxBackoff      = time.Minute
+	defaultDebounce        = 5 * time.Second
+	defaultBatchMaxWait    = 30 * time.Second
+	defaultMaxBatch        = 20
+	defaultQueueSize       = 1000
+	defaultDeadLetterLimit = 200
+)
+
+var ErrQueueFull = errors.New("notification queue is full")
+
+// QueueOptions 는 알림 대상마다 따로 적용되는 전달 옵션
+type QueueOptions struct {
+	QPS         float32
+	Burst       int
+	MaxRetries  int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Debounce 동안 새 메시지가 없을 때까지 모아서 하나의 digest 로 보낸다. 0 이면 모으지 않는다
+	Debounce     time.Duration
+	BatchMaxWait time.Duration
+	MaxBatch     int
+	QueueSize    int
+}
+
+// QueueOptionsFromEnv 는 NOTIFY_QPS, NOTIFY_BURST, NOTIFY_MAX_RETRIES, NOTIFY_BACKOFF, NOTIFY_MAX_BACKOFF,
+// NOTIFY_DEBOUNCE, NOTIFY_BATCH_MAX_WAIT, NOTIFY_MAX_BATCH, NOTIFY_QUEUE_SIZE 로 옵션을 만든다
+func QueueOptionsFromEnv() QueueOptions {
+	opts := QueueOptions{
+		QPS:          defaultQueueQPS,
+		Burst:        defaultQueueBurst,
+		MaxRetries:   defaultMaxRetries,
+		BaseBackoff:  defaultBaseBackoff,
+		MaxBackoff:   defaultMaxBackoff,
+		Debounce:     defaultDebounce,
+		BatchMaxWait: defaultBatchMaxWait,
+		MaxBatch:     defaultMaxBatch,
+		QueueSize:    defaultQueueSize,
+	}
+	if qps, err := strconv.ParseFloat(os.Getenv("NOTIFY_QPS"), 32); err == nil && qps > 0 {
+		opts.QPS = float32(qps)
+	}
+	if burst, err := strconv.Atoi(os.Getenv("NOTIFY_BURST")); err == nil && burst > 0 {
+		opts.Burst = burst
+	}
+	if retries, err := strconv.Atoi(os.Getenv("NOTIFY_MAX_RETRIES")); err == nil && retries >= 0 {
+		opts.MaxRetries = retries
+	}
+	if backoff, err := time.ParseDuration(os.Getenv("NOTIFY_BACKOFF")); err == nil && backoff > 0 {
+		opts.BaseBackoff = backoff
+	}
+	if backoff, err := time.ParseDuration(os.Getenv("NOTIFY_MAX_BACKOFF")); err == nil && backoff > 0 {
+		opts.MaxBackoff = backoff
+	}
+	if debounce, err := time.ParseDuration(os.Getenv("NOTIFY_DEBOUNCE")); err == nil && debounce >= 0 {
+		opts.Debounce = debounce
+	}
+	if wait, err := time.ParseDuration(os.Getenv("NOTIFY_BATCH_MAX_WAIT")); err == nil && wait > 0 {
+		opts.BatchMaxWait = wait
+	}
+	if batch, err := strconv.Atoi(os.Getenv("NOTIFY_MAX_BATCH")); err == nil && batch > 0 {
+		opts.MaxBatch = batch
+	}
+	if size, err := strconv.Atoi(os.Getenv("NOTIFY_QUEUE_SIZE")); err == nil && size > 0 {
+		opts.QueueSize = size
+	}
+	return opts
+}
+
+// Queued 는 알림 대상 하나에 대한 전달 큐. Notify 는 큐에 넣기만 하고, 별도 goroutine 이
+// rate limit 을 지키며 묶어서 보내고 실패하면 재시도한다. 끝내 실패한 메시지는 dead letter 로 남긴다
+type Queued struct {
+	backend     Notifier
+	options     QueueOptions
+	limiter     flowcontrol.RateLimiter
+	deadLetters *DeadLetters
+	queue       chan Message
+}
+
+func NewQueued(backend Notifier, options QueueOptions, deadLetters *DeadLetters) *Queued {
+	q := &Queued{
+		backend:     backend,
+		options:     options,
+		limiter:     flowcontrol.NewTokenBucketRateLimiter(options.QPS, options.Burst),
+		deadLetters: deadLetters,
+		queue:       make(chan Message, options.QueueSize),
+	}
+	go q.run()
+	return q
+}
+
+func (q *Queued) Name() string {
+	return q.backend.Name()
+}
+
+func (q *Queued) Notify(ctx context.Context, msg Message) error {
+	select {
+	case q.queue <- msg:
+		return nil
+	default:
+		q.deadLetters.add(q.Name(), msg, ErrQueueFull, 0)
+		return ErrQueueFull
+	}
+}
+
+func (q *Queued) run() {
+	for msg := range q.queue {
+		for _, batch := range q.collect(msg) {
+			q.deliver(digest(batch))
+		}
+	}
+}
+
+// collect 는 Debounce 동안 조용해지거나 BatchMaxWait/MaxBatch 에 닿을 때까지 메시지를 모은다.
+// critical 메시지와 템플릿이 만든 payload(합칠 수 없음)는 기다리지 않도록, 들어오면 모으던 것을 끊고 먼저 보낸다
+func (q *Queued) collect(first Message) [][]Message {
+	if q.options.Debounce <= 0 || !batchable(first) {
+		return [][]Message{{first}}
+	}
+
+	batch := []Message{first}
+	deadline := time.NewTimer(q.options.BatchMaxWait)
+	defer deadline.Stop()
+	quiet := time.NewTimer(q.options.Debounce)
+	defer quiet.Stop()
+
+	for len(batch) < q.options.MaxBatch {
+		select {
+		case msg := <-q.queue:
+			if !batchable(msg) {
+				return [][]Message{{msg}, batch}
+			}
+			batch = append(batch, msg)
+			if !quiet.Stop() {
+				<-quiet.C
+			}
+			quiet.Reset(q.options.Debounce)
+		case <-quiet.C:
+			return [][]Message{batch}
+		case <-deadline.C:
+			return [][]Message{batch}
+		}
+	}
+	return [][]Message{batch}
+}
+
+func batchable(msg Message) bool {
+	return msg.Severity != SeverityCritical && len(msg.Payload) == 0
+}
+
+// digest 는 여러 메시지를 하나로 합친다. severity 는 가장 높은 것을 따른다
+func digest(batch []Message) Message {
+	if len(batch) == 1 {
+		return batch[0]
+	}
+	sections := make([]string, 0, len(batch))
+	severity := SeverityInfo
+	for _, msg := range batch {
+		sections = append(sections, fmt.Sprintf("*%s*\n%s", msg.Title, msg.Text))
+		if severityRank[msg.Severity] > severityRank[severity] {
+			severity = msg.Severity
+		}
+	}
+	return Message{
+		Event:    "digest",
+		Title:    fmt.Sprintf("%d notifications", len(batch)),
+		Text:     strings.Join(sections, "\n\n"),
+		Severity: severity,
+	}
+}
+
+var severityRank = map[string]int{
+	SeverityInfo:     0,
+	SeverityWarning:  1,
+	SeverityCritical: 2,
+}
+
+func (q *Queued) deliver(msg Message) {
+	ctx := context.Background()
+	var err error
+	attempts := 0
+	for attempts <= q.options.MaxRetries {
+		if err = q.limiter.Wait(ctx); err != nil {
+			break
+		}
+		attempts++
+		if err = q.backend.Notify(ctx, msg); err == nil {
+			return
+		}
+
+		wait, retryable := q.backoff(err, attempts)
+		if !retryable || attempts > q.options.MaxRetries {
+			break
+		}
+		log.WithError(err).Warnf("Notification to %s failed, retrying in %s", q.Name(), wait)
+		time.Sleep(wait)
+	}
+	log.WithError(err).Errorf("Notification to %s failed after %d attempts", q.Name(), attempts)
+	q.deadLetters.add(q.Name(), msg, err, attempts)
+}
+
+// backoff 는 다음 재시도까지 기다릴 시간을 정한다. 429/5xx 가 아닌 HTTP 오류는 재시도하지 않는다
+func (q *Queued) backoff(err error, attempts int) (time.Duration, bool) {
+	wait := q.options.BaseBackoff << (attempts - 1)
+	if wait > q.options.MaxBackoff || wait <= 0 {
+		wait = q.options.MaxBackoff
+	}
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return wait, true
+	}
+	if httpErr.StatusCode != http.StatusTooManyRequests && httpErr.StatusCode < 500 {
+		return 0, false
+	}
+	if retryAfter, ok := parseRetryAfter(httpErr.Header.Get("Retry-After")); ok {
+		return retryAfter, true
+	}
+	return wait, true
+}
+
+// parseRetryAfter 는 초 단위 숫자와 HTTP 날짜 형식을 모두 받는다
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,213 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedServer 는 요청마다 statuses 의 응답 코드를 차례로 돌려주고, 다 쓰면 200 을 준다
+type scriptedServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header
+	bodies   chan Message
+}
+
+func newScriptedServer(t *testing.T, statuses ...int) *scriptedServer {
+	t.Helper()
+	s := &scriptedServer{statuses: statuses, header: http.Header{}, bodies: make(chan Message, 20)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var msg Message
+		_ = json.Unmarshal(data, &msg)
+
+		s.mu.Lock()
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		for key, values := range s.header {
+			w.Header()[key] = values
+		}
+		s.mu.Unlock()
+
+		w.WriteHeader(status)
+		if status < 300 {
+			s.bodies <- msg
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func testQueueOptions() QueueOptions {
+	return QueueOptions{
+		QPS:          1000,
+		Burst:        100,
+		MaxRetries:   3,
+		BaseBackoff:  time.Millisecond,
+		MaxBackoff:   10 * time.Millisecond,
+		BatchMaxWait: time.Second,
+		MaxBatch:     10,
+		QueueSize:    10,
+	}
+}
+
+func waitForDeadLetters(t *testing.T, deadLetters *DeadLetters, count int) []DeadLetter {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if letters := deadLetters.List(); len(letters) >= count {
+			return letters
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d dead letters, got %d", count, len(deadLetters.List()))
+	return nil
+}
+
+func receive(t *testing.T, bodies <-chan Message) Message {
+	t.Helper()
+	select {
+	case msg := <-bodies:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a delivered message")
+		return Message{}
+	}
+}
+
+func TestQueuedRetriesServerErrors(t *testing.T) {
+	server := newScriptedServer(t, http.StatusInternalServerError, http.StatusBadGateway)
+	deadLetters := &DeadLetters{limit: 10}
+	q := NewQueued(NewWebhook("webhook", server.URL, nil), testQueueOptions(), deadLetters)
+
+	if err := q.Notify(context.Background(), Message{Title: "retry me", Severity: SeverityInfo}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, server.bodies); msg.Title != "retry me" {
+		t.Errorf("delivered %q, want %q", msg.Title, "retry me")
+	}
+	if letters := deadLetters.List(); len(letters) != 0 {
+		t.Errorf("unexpected dead letters %v", letters)
+	}
+}
+
+func TestQueuedDeadLettersAfterMaxRetries(t *testing.T) {
+	server := newScriptedServer(t, 500, 500, 500, 500, 500)
+	deadLetters := &DeadLetters{limit: 10}
+	q := NewQueued(NewWebhook("webhook", server.URL, nil), testQueueOptions(), deadLetters)
+
+	_ = q.Notify(context.Background(), Message{Title: "always fails"})
+	letter := waitForDeadLetters(t, deadLetters, 1)[0]
+	// 첫 시도 + MaxRetries 번 재시도
+	if letter.Attempts != 4 || letter.Destination != "webhook" || letter.Message.Title != "always fails" {
+		t.Errorf("unexpected dead letter %+v", letter)
+	}
+}
+
+func TestQueuedDoesNotRetryClientErrors(t *testing.T) {
+	server := newScriptedServer(t, http.StatusBadRequest)
+	deadLetters := &DeadLetters{limit: 10}
+	q := NewQueued(NewWebhook("webhook", server.URL, nil), testQueueOptions(), deadLetters)
+
+	_ = q.Notify(context.Background(), Message{Title: "bad request"})
+	if letter := waitForDeadLetters(t, deadLetters, 1)[0]; letter.Attempts != 1 {
+		t.Errorf("attempts = %d, want 1", letter.Attempts)
+	}
+}
+
+func TestQueuedFullQueue(t *testing.T) {
+	deadLetters := &DeadLetters{limit: 10}
+	// run goroutine 없이 큐만 채운다
+	q := &Queued{backend: NewWebhook("webhook", "http://127.0.0.1:0", nil), options: testQueueOptions(), deadLetters: deadLetters, queue: make(chan Message, 1)}
+
+	if err := q.Notify(context.Background(), Message{Title: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Notify(context.Background(), Message{Title: "second"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("err = %v, want ErrQueueFull", err)
+	}
+	letters := deadLetters.List()
+	if len(letters) != 1 || letters[0].Message.Title != "second" || letters[0].Error != ErrQueueFull.Error() {
+		t.Errorf("unexpected dead letters %+v", letters)
+	}
+}
+
+func TestQueuedBatchesIntoDigest(t *testing.T) {
+	server := newScriptedServer(t)
+	options := testQueueOptions()
+	options.Debounce = 50 * time.Millisecond
+	q := NewQueued(NewWebhook("webhook", server.URL, nil), options, &DeadLetters{limit: 10})
+
+	_ = q.Notify(context.Background(), Message{Title: "a", Severity: SeverityInfo})
+	_ = q.Notify(context.Background(), Message{Title: "b", Severity: SeverityWarning})
+	msg := receive(t, server.bodies)
+	if msg.Title != "2 notifications" || msg.Severity != SeverityWarning {
+		t.Errorf("digest = %+v", msg)
+	}
+}
+
+func TestQueuedSendsUrgentMessagesWithoutWaiting(t *testing.T) {
+	server := newScriptedServer(t)
+	options := testQueueOptions()
+	options.Debounce = time.Minute
+	options.BatchMaxWait = time.Minute
+	q := NewQueued(NewWebhook("webhook", server.URL, nil), options, &DeadLetters{limit: 10})
+
+	_ = q.Notify(context.Background(), Message{Title: "batched", Severity: SeverityInfo})
+	_ = q.Notify(context.Background(), Message{Title: "critical", Severity: SeverityCritical})
+	_ = q.Notify(context.Background(), Message{Title: "payload", Payload: json.RawMessage(`{"title":"payload"}`)})
+
+	// Debounce 를 기다리지 않고 critical, 모으던 메시지, payload 순서로 보낸다
+	for _, want := range []string{"critical", "batched", "payload"} {
+		if msg := receive(t, server.bodies); msg.Title != want {
+			t.Errorf("delivered %q, want %q", msg.Title, want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	q := &Queued{options: QueueOptions{BaseBackoff: time.Second, MaxBackoff: 5 * time.Second}}
+	tests := []struct {
+		name      string
+		err       error
+		attempts  int
+		wait      time.Duration
+		retryable bool
+	}{
+		{"network error", errors.New("connection refused"), 1, time.Second, true},
+		{"exponential", errors.New("timeout"), 3, 4 * time.Second, true},
+		{"capped", errors.New("timeout"), 10, 5 * time.Second, true},
+		{"server error", &HTTPError{StatusCode: 503, Header: http.Header{}}, 2, 2 * time.Second, true},
+		{"client error", &HTTPError{StatusCode: 404, Header: http.Header{}}, 1, 0, false},
+		{"retry after", &HTTPError{StatusCode: 429, Header: http.Header{"Retry-After": []string{"7"}}}, 1, 7 * time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, retryable := q.backoff(tt.err, tt.attempts)
+			if wait != tt.wait || retryable != tt.retryable {
+				t.Errorf("backoff = (%s, %v), want (%s, %v)", wait, retryable, tt.wait, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestDeadLettersLimit(t *testing.T) {
+	deadLetters := &DeadLetters{limit: 2}
+	for _, title := range []string{"a", "b", "c"} {
+		deadLetters.add("slack", Message{Title: title}, errors.New("failed"), 1)
+	}
+	letters := deadLetters.List()
+	if len(letters) != 2 || letters[0].Message.Title != "b" || letters[1].Message.Title != "c" {
+		t.Errorf("unexpected dead letters %+v", letters)
+	}
+}
@@ -0,0 +1,43 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+)
+
+type Slack struct {
+	name       string
+	webhookURL string
+	client     *http.Client
+}
+
+func NewSlack(name, webhookURL string) *Slack {
+	return &Slack{name: name, webhookURL: webhookURL, client: newHTTPClient()}
+}
+
+func (s *Slack) Name() string {
+	return s.name
+}
+
+func (s *Slack) Notify(ctx context.Context, msg Message) error {
+	if len(msg.Payload) > 0 {
+		return postJSON(ctx, s.client, s.webhookURL, nil, msg.Payload)
+	}
+	text := msg.Text
+	if msg.Title != "" {
+		text = "*" + msg.Title + "*\n" + text
+	}
+	payload := map[string]interface{}{
+		"text": msg.Title,
+		"blocks": []map[string]interface{}{
+			{
+				"type": "section",
+				"text": map[string]string{
+					"type": "mrkdwn",
+					"text": text,
+				},
+			},
+		},
+	}
+	return postJSON(ctx, s.client, s.webhookURL, nil, payload)
+}
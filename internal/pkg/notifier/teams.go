@@ -0,0 +1,44 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+var teamsThemeColors = map[string]string{
+	SeverityInfo:     "2EB886",
+	SeverityWarning:  "DAA038",
+	SeverityCritical: "A30200",
+}
+
+// Teams 는 Microsoft Teams incoming webhook 으로 MessageCard 를 보낸다
+type Teams struct {
+	name       string
+	webhookURL string
+	client     *http.Client
+}
+
+func NewTeams(name, webhookURL string) *Teams {
+	return &Teams{name: name, webhookURL: webhookURL, client: newHTTPClient()}
+}
+
+func (t *Teams) Name() string {
+	return t.name
+}
+
+func (t *Teams) Notify(ctx context.Context, msg Message) error {
+	if len(msg.Payload) > 0 {
+		return postJSON(ctx, t.client, t.webhookURL, nil, msg.Payload)
+	}
+	payload := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "http://schema.org/extensions",
+		"summary":    msg.Title,
+		"title":      msg.Title,
+		"themeColor": teamsThemeColors[msg.Severity],
+		// Teams markdown 은 줄바꿈에 공백 두 칸이 필요하다
+		"text": strings.ReplaceAll(msg.Text, "\n", "  \n"),
+	}
+	return postJSON(ctx, t.client, t.webhookURL, nil, payload)
+}
@@ -0,0 +1,154 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplateFS embed.FS
+
+const templateSuffix = ".tmpl"
+
+// Templates 는 알림 본문을 만드는 text/template 모음. 기본 템플릿은 바이너리에 포함되어 있고,
+// NOTIFY_TEMPLATE_DIR 의 *.tmpl 파일이 기본 템플릿보다 먼저 쓰인다.
+//
+// 템플릿은 아래 순서로 찾는다 (event 가 deployment.updated, backend 가 slack 인 경우).
+// NOTIFY_TEMPLATE_DIR 에서 먼저 찾고, 없으면 같은 순서로 기본 템플릿에서 찾는다
+//
+//	deployment.updated.slack.tmpl, deployment.updated.tmpl,
+//	updated.slack.tmpl, updated.tmpl, default.slack.tmpl, default.tmpl
+//
+// 기본 템플릿은 event 이름 전체(deployment.updated, image.policy.violation 등)로만 정의해서
+// event.created 처럼 뒷부분만 같은 다른 event 에 쓰이지 않게 한다.
+//
+// 템플릿에는 Message 가 그대로 전달된다({{.Title}}, {{.Text}}, {{.Namespace}}, {{.Data}} 등).
+// slack, teams, discord, webhook 은 결과가 JSON 객체이면 그대로 payload 로 보내므로 Block Kit 같은 레이아웃을 쓸 수 있다
+type Templates struct {
+	set *template.Template
+	// custom 은 NOTIFY_TEMPLATE_DIR 에서 읽은 템플릿 이름
+	custom map[string]bool
+}
+
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	// json 은 값을 JSON 으로 만든다. JSON payload 템플릿 안에 문자열을 넣을 때 escape 용도로 쓴다
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// LoadTemplatesFromEnv 는 기본 템플릿에 NOTIFY_TEMPLATE_DIR 의 템플릿을 더한다
+func LoadTemplatesFromEnv() (*Templates, error) {
+	return LoadTemplates(os.Getenv("NOTIFY_TEMPLATE_DIR"))
+}
+
+func LoadTemplates(dir string) (*Templates, error) {
+	set, err := template.New("").Funcs(templateFuncs).ParseFS(defaultTemplateFS, "templates/*"+templateSuffix)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return &Templates{set: set}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+templateSuffix))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		log.Warnf("No notification templates found in %s, using defaults", dir)
+		return &Templates{set: set}, nil
+	}
+	if set, err = set.ParseFiles(files...); err != nil {
+		return nil, fmt.Errorf("invalid notification template: %w", err)
+	}
+	custom := make(map[string]bool, len(files))
+	for _, file := range files {
+		custom[filepath.Base(file)] = true
+	}
+	return &Templates{set: set, custom: custom}, nil
+}
+
+func (t *Templates) lookup(event, backendType string) *template.Template {
+	var names []string
+	for _, base := range templateBases(event) {
+		names = append(names, base+"."+backendType+templateSuffix, base+templateSuffix)
+	}
+	for _, name := range names {
+		if t.custom[name] {
+			return t.set.Lookup(name)
+		}
+	}
+	for _, name := range names {
+		if tmpl := t.set.Lookup(name); tmpl != nil {
+			return tmpl
+		}
+	}
+	return nil
+}
+
+// templateBases 는 deployment.rollout.failed → [deployment.rollout.failed, rollout.failed, default]
+func templateBases(event string) []string {
+	var bases []string
+	if event != "" {
+		bases = append(bases, event)
+		if _, suffix, ok := strings.Cut(event, "."); ok {
+			bases = append(bases, suffix)
+		}
+	}
+	return append(bases, "default")
+}
+
+// Render 는 backend 에 맞는 템플릿으로 Text(또는 JSON payload) 를 만든다
+func (t *Templates) Render(backendType string, msg Message) (Message, error) {
+	tmpl := t.lookup(msg.Event, backendType)
+	if tmpl == nil {
+		return msg, nil
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, msg); err != nil {
+		return msg, err
+	}
+
+	output := strings.TrimSpace(buf.String())
+	if backendType != TypeEmail && strings.HasPrefix(output, "{") && json.Valid([]byte(output)) {
+		msg.Payload = json.RawMessage(output)
+		return msg, nil
+	}
+	msg.Text = output
+	return msg, nil
+}
+
+// Templated 는 메시지를 템플릿으로 렌더링한 뒤 내부 notifier 로 넘긴다
+type Templated struct {
+	Notifier
+	backendType string
+	templates   *Templates
+}
+
+func NewTemplated(n Notifier, backendType string, templates *Templates) *Templated {
+	return &Templated{Notifier: n, backendType: backendType, templates: templates}
+}
+
+func (t *Templated) Notify(ctx context.Context, msg Message) error {
+	rendered, err := t.templates.Render(t.backendType, msg)
+	if err != nil {
+		// 템플릿 오류로 알림을 잃지 않도록 원래 메시지를 보낸다
+		log.WithError(err).Warnf("Failed to render notification template for %s", t.Name())
+		rendered = msg
+	}
+	return t.Notifier.Notify(ctx, rendered)
+}
@@ -0,0 +1,94 @@
+package notifier
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testChangeData struct {
+	Action      string
+	Kind        string
+	Namespace   string
+	Name        string
+	Attribution []string
+	Changes     []string
+}
+
+func TestRenderDefaultTemplates(t *testing.T) {
+	templates, err := LoadTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	change := Message{
+		Event: "deployment.updated",
+		Text:  "plain text",
+		Data: testChangeData{
+			Action: "Updated", Kind: "Deployment", Namespace: "prd-api", Name: "api",
+			Changes: []string{"- Replicas changed from `1` to `2`"},
+		},
+	}
+	rendered, err := templates.Render(TypeSlack, change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "*Updated Deployment:* `prd-api/api`\n- Replicas changed from `1` to `2`"; rendered.Text != want {
+		t.Errorf("Text = %q, want %q", rendered.Text, want)
+	}
+
+	// 뒷부분이 같은 다른 event 는 워크로드 템플릿을 쓰지 않는다
+	for _, event := range []string{"event.created", "event.deleted", "pod.updated"} {
+		msg := Message{Event: event, Text: "plain text", Data: struct{ Reason string }{"Created"}}
+		rendered, err := templates.Render(TypeSlack, msg)
+		if err != nil {
+			t.Fatalf("%s: %v", event, err)
+		}
+		if rendered.Text != "plain text" {
+			t.Errorf("%s rendered %q, want the default template", event, rendered.Text)
+		}
+	}
+}
+
+func TestCustomTemplatesTakePrecedence(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"updated.tmpl":       "custom {{.Event}}",
+		"updated.teams.tmpl": `{"text": {{json .Event}}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 사용자 updated.tmpl 이 기본 deployment.updated 템플릿보다 먼저 쓰인다
+	rendered, err := templates.Render(TypeSlack, Message{Event: "deployment.updated"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered.Text != "custom deployment.updated" {
+		t.Errorf("Text = %q", rendered.Text)
+	}
+
+	// JSON 객체 결과는 payload 가 된다. email 은 항상 Text 로 받는다
+	rendered, err = templates.Render(TypeTeams, Message{Event: "deployment.updated"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rendered.Payload) != `{"text": "deployment.updated"}` {
+		t.Errorf("Payload = %s", rendered.Payload)
+	}
+	rendered, err = templates.Render(TypeEmail, Message{Event: "deployment.updated"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rendered.Payload) != 0 || !strings.HasPrefix(rendered.Text, "custom") {
+		t.Errorf("email rendered %+v", rendered)
+	}
+}
@@ -0,0 +1,46 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Webhook 은 Message 를 그대로 JSON 으로 보내는 범용 webhook
+type Webhook struct {
+	name    string
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func NewWebhook(name, url string, headers map[string]string) *Webhook {
+	return &Webhook{name: name, url: url, headers: headers, client: newHTTPClient()}
+}
+
+func (w *Webhook) Name() string {
+	return w.name
+}
+
+func (w *Webhook) Notify(ctx context.Context, msg Message) error {
+	if len(msg.Payload) > 0 {
+		return postJSON(ctx, w.client, w.url, w.headers, msg.Payload)
+	}
+	payload := map[string]interface{}{
+		"event":     msg.Event,
+		"title":     msg.Title,
+		"text":      msg.Text,
+		"severity":  msg.Severity,
+		"timestamp": time.Now().UTC(),
+	}
+	if msg.Namespace != "" {
+		payload["namespace"] = msg.Namespace
+	}
+	if len(msg.ChangeTypes) > 0 {
+		payload["changeTypes"] = msg.ChangeTypes
+	}
+	if msg.Data != nil {
+		payload["data"] = msg.Data
+	}
+	return postJSON(ctx, w.client, w.url, w.headers, payload)
+}
@@ -0,0 +1,100 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Format string
+
+const (
+	CSV    Format = "csv"
+	JSON   Format = "json"
+	NDJSON Format = "ndjson"
+)
+
+// Table 은 같은 데이터를 CSV(헤더 + 행) 와 JSON(레코드) 두 가지 형태로 제공
+type Table interface {
+	Header() []string
+	Rows() [][]string
+	Records() []interface{}
+}
+
+func ParseFormat(format string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(format))) {
+	case CSV:
+		return CSV, nil
+	case JSON:
+		return JSON, nil
+	case NDJSON, "jsonl":
+		return NDJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported report format %q", format)
+	}
+}
+
+// FormatFromContentType 은 Accept 협상 결과로 받은 MIME 타입을 Format 으로 변환
+func FormatFromContentType(contentType string) (Format, bool) {
+	for _, f := range []Format{CSV, JSON, NDJSON} {
+		if f.ContentType() == contentType {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+func ContentTypes() []string {
+	return []string{JSON.ContentType(), CSV.ContentType(), NDJSON.ContentType()}
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case CSV:
+		return "text/csv"
+	case NDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/json"
+	}
+}
+
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// Write 는 table 을 format 에 맞춰 w 에 바로 기록한다
+func Write(w io.Writer, format Format, table Table) error {
+	switch format {
+	case CSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(table.Header()); err != nil {
+			return err
+		}
+		for _, row := range table.Rows() {
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case NDJSON:
+		encoder := json.NewEncoder(w)
+		for _, record := range table.Records() {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	case JSON:
+		records := table.Records()
+		if records == nil {
+			records = []interface{}{}
+		}
+		return json.NewEncoder(w).Encode(records)
+	default:
+		return fmt.Errorf("unsupported report format %q", format)
+	}
+}
@@ -0,0 +1,119 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrStoreDisabled = errors.New("report directory is not configured (set REPORT_DIR)")
+
+// 같은 초에 저장한 리포트가 서로 덮어쓰지 않도록 나노초까지 이름에 넣는다
+const nameTimeLayout = "20060102T150405.000000000Z"
+
+// Store 는 생성된 리포트 사본을 Dir 아래에 보관
+type Store struct {
+	Dir string
+}
+
+type StoredReport struct {
+	Name      string
+	Size      int64
+	CreatedAt time.Time
+}
+
+func NewStoreFromEnv() Store {
+	return Store{Dir: os.Getenv("REPORT_DIR")}
+}
+
+func (s Store) Enabled() bool {
+	return s.Dir != ""
+}
+
+// Save 는 <prefix>-<timestamp>.<format> 파일로 table 을 저장하고 파일 이름을 반환
+func (s Store) Save(prefix string, format Format, table Table) (string, error) {
+	if !s.Enabled() {
+		return "", ErrStoreDisabled
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s-%s%s", prefix, time.Now().UTC().Format(nameTimeLayout), format.Extension())
+	// 쓰다가 실패한 리포트가 목록에 보이지 않도록 임시 파일에 쓴 뒤 link 한다.
+	// rename 과 달리 link 는 같은 이름의 파일이 있으면 실패하므로 기존 리포트를 덮어쓰지 않는다
+	file, err := os.CreateTemp(s.Dir, "."+name+".*")
+	if err != nil {
+		return "", err
+	}
+	tmp := file.Name()
+	defer os.Remove(tmp)
+	if err := Write(file, format, table); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Link(tmp, filepath.Join(s.Dir, name)); err != nil {
+		if os.IsExist(err) {
+			return "", fmt.Errorf("report %s already exists", name)
+		}
+		return "", err
+	}
+	return name, nil
+}
+
+func (s Store) List() ([]StoredReport, error) {
+	if !s.Enabled() {
+		return nil, ErrStoreDisabled
+	}
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []StoredReport{}, nil
+		}
+		return nil, err
+	}
+
+	reports := make([]StoredReport, 0, len(entries))
+	for _, entry := range entries {
+		// 점으로 시작하는 파일은 아직 쓰는 중인 임시 파일
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		reports = append(reports, StoredReport{
+			Name:      entry.Name(),
+			Size:      info.Size(),
+			CreatedAt: info.ModTime(),
+		})
+	}
+	// 최신 리포트가 먼저 오도록 정렬
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].CreatedAt.After(reports[j].CreatedAt)
+	})
+	return reports, nil
+}
+
+// Path 는 name 에 해당하는 파일 경로를 반환한다. Dir 밖의 경로는 허용하지 않는다.
+func (s Store) Path(name string) (string, error) {
+	if !s.Enabled() {
+		return "", ErrStoreDisabled
+	}
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid report name %q", name)
+	}
+	path := filepath.Join(s.Dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}